@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+const adminUsage = `usage: api admin <command>
+
+commands:
+  grant USERNAME   make the user a platform administrator
+  revoke USERNAME  take platform administration away from the user`
+
+// runAdmin implements the admin subcommand, which is the only way to grant
+// platform administration: the API never lets a user raise their own rights.
+func runAdmin(cfg *config.Config, args []string) error {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		return fmt.Errorf("%s", adminUsage)
+	}
+	isAdmin := args[0] == "grant"
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db.GetDB())
+	user, err := users.GetByUsername(ctx, model.NormalizeUsername(args[1]))
+	if err != nil {
+		return fmt.Errorf("finding user %q: %w", args[1], err)
+	}
+	if user.IsAdmin == isAdmin {
+		fmt.Println("No change to", user.Username)
+		return nil
+	}
+
+	user.IsAdmin = isAdmin
+	if err := users.Update(ctx, user); err != nil {
+		return fmt.Errorf("updating user %q: %w", user.Username, err)
+	}
+	if isAdmin {
+		fmt.Println("Granted administration to", user.Username)
+	} else {
+		fmt.Println("Revoked administration from", user.Username)
+	}
+	return nil
+}
@@ -1,20 +1,87 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/logging"
 	"github.com/ruslanguns/go-chat/internal/server"
+	"github.com/ruslanguns/go-chat/internal/tracing"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(cfg, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger := logging.New(os.Stderr, cfg.Logging)
+	slog.SetDefault(logger)
+	logger.Info("configuration loaded", "config", cfg)
+
+	// serve returns only once its deferred calls, such as flushing traces,
+	// have run, so exiting here does not cut them short.
+	if err := serve(cfg, logger); err != nil {
+		logger.Error("server stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
 
-	port := os.Getenv("PORT")
-	server := server.NewServer()
+// serve runs the server until it is told to shut down.
+func serve(cfg *config.Config, logger *slog.Logger) error {
+	// The first SIGINT or SIGTERM starts a graceful shutdown; once it has
+	// begun, another one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		// Flush the spans of the last requests, even after a slow drain.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
 
-	fmt.Printf("Server is running on :%s...\n", port)
-	err := server.ListenAndServe()
+	db, err := database.New(cfg.Database)
 	if err != nil {
-		panic(fmt.Sprintf("cannot start server: %s", err))
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		return fmt.Errorf("running migrations: %w", err)
 	}
+
+	server := server.NewServer(cfg, db)
+
+	logger.Info("server listening", "port", cfg.Server.Port)
+	return server.Run(ctx)
 }
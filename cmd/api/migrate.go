@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up                    apply all pending migrations
+  down [N]              roll back the last N migrations (default 1)
+  status                list migrations and whether they are applied
+  create [-dir D] NAME  add empty up and down files for a new migration`
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	if args[0] == "create" {
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		dir := fs.String("dir", "internal/database/migrations", "migrations source directory")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		files, err := database.CreateMigration(*dir, fs.Arg(0))
+		for _, file := range files {
+			fmt.Println("Created", file)
+		}
+		return err
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		for _, migration := range applied {
+			fmt.Println("Applied", migration)
+		}
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(n)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		for _, migration := range rolledBack {
+			fmt.Println("Rolled back", migration)
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%s\n", status.Migration, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("%s", migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+)
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	sessionKey
+)
+
+// WithUser returns a copy of ctx carrying the authenticated user and the
+// session their token belongs to.
+func WithUser(ctx context.Context, user *model.User, sessionID domain.EntityID) context.Context {
+	ctx = context.WithValue(ctx, userKey, user)
+	return context.WithValue(ctx, sessionKey, sessionID)
+}
+
+// UserFromContext returns the authenticated user, if any.
+func UserFromContext(ctx context.Context) (*model.User, bool) {
+	user, ok := ctx.Value(userKey).(*model.User)
+	return user, ok
+}
+
+// SessionIDFromContext returns the session of the authenticated user, if any.
+func SessionIDFromContext(ctx context.Context) (domain.EntityID, bool) {
+	sessionID, ok := ctx.Value(sessionKey).(domain.EntityID)
+	return sessionID, ok
+}
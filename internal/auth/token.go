@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+// Tokens is the credential pair handed to a client after login or refresh.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Claims identifies the user and session an access token was issued for.
+type Claims struct {
+	UserID    domain.EntityID
+	SessionID domain.EntityID
+}
+
+type tokenClaims struct {
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// TokenIssuer signs and verifies HMAC-SHA256 access tokens.
+type TokenIssuer struct {
+	secret    []byte
+	accessTTL time.Duration
+}
+
+func NewTokenIssuer(secret []byte, accessTTL time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secret:    secret,
+		accessTTL: accessTTL,
+	}
+}
+
+// AccessTTL returns how long issued access tokens remain valid.
+func (i *TokenIssuer) AccessTTL() time.Duration {
+	return i.accessTTL
+}
+
+// Issue signs an access token for the given user and session.
+func (i *TokenIssuer) Issue(userID, sessionID domain.EntityID, now time.Time) (string, error) {
+	claims := tokenClaims{
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.accessTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+}
+
+// Parse verifies the signature and expiry of an access token.
+func (i *TokenIssuer) Parse(token string) (*Claims, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return i.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	userID, err := domain.ParseEntityID(claims.Subject)
+	if err != nil {
+		return nil, errors.New("invalid access token: bad subject")
+	}
+	sessionID, err := domain.ParseEntityID(claims.SessionID)
+	if err != nil {
+		return nil, errors.New("invalid access token: bad session")
+	}
+
+	return &Claims{UserID: userID, SessionID: sessionID}, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token.
+func NewRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashRefreshToken returns the form of a refresh token that is persisted.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+func TestIssueAndParse(t *testing.T) {
+	issuer := NewTokenIssuer([]byte("secret"), time.Minute)
+	userID, sessionID := domain.NewEntityID(), domain.NewEntityID()
+
+	token, err := issuer.Issue(userID, sessionID, time.Now())
+	if err != nil {
+		t.Fatalf("error issuing token. Err: %v", err)
+	}
+
+	claims, err := issuer.Parse(token)
+	if err != nil {
+		t.Fatalf("error parsing token. Err: %v", err)
+	}
+	if claims.UserID != userID || claims.SessionID != sessionID {
+		t.Errorf("expected claims for %s/%s; got %s/%s", userID, sessionID, claims.UserID, claims.SessionID)
+	}
+}
+
+func TestParseRejectsExpiredToken(t *testing.T) {
+	issuer := NewTokenIssuer([]byte("secret"), time.Minute)
+
+	token, _ := issuer.Issue(domain.NewEntityID(), domain.NewEntityID(), time.Now().Add(-time.Hour))
+	if _, err := issuer.Parse(token); err == nil {
+		t.Errorf("expected expired token to be rejected")
+	}
+}
+
+func TestParseRejectsForeignSignature(t *testing.T) {
+	token, _ := NewTokenIssuer([]byte("other"), time.Minute).Issue(domain.NewEntityID(), domain.NewEntityID(), time.Now())
+
+	if _, err := NewTokenIssuer([]byte("secret"), time.Minute).Parse(token); err == nil {
+		t.Errorf("expected token signed with another key to be rejected")
+	}
+}
@@ -0,0 +1,444 @@
+// Package config loads the application configuration. Settings come from, in
+// increasing order of precedence: built-in defaults, an optional YAML or TOML
+// file named by CONFIG_FILE, a .env file and the process environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported database drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Supported log formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Supported trace exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Logging   LoggingConfig   `yaml:"logging" toml:"logging"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+type ServerConfig struct {
+	Port         int           `yaml:"port" toml:"port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout bounds how long a shutdown waits for in-flight requests
+	// and connections to finish.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// ShutdownDelay is how long a shutdown keeps serving while reporting
+	// unhealthy, giving load balancers time to stop routing to the server.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+}
+
+type DatabaseConfig struct {
+	// Driver is either DriverSQLite or DriverPostgres.
+	Driver string `yaml:"driver" toml:"driver"`
+	// URL is the SQLite database file or the PostgreSQL connection string.
+	URL string `yaml:"url" toml:"url"`
+}
+
+type AuthConfig struct {
+	// Secret signs access tokens. When empty a random key is generated at
+	// startup, so tokens do not survive a restart.
+	Secret         string        `yaml:"secret" toml:"secret"`
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+}
+
+type LoggingConfig struct {
+	// Level is the minimum level logged: debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+	// Format is FormatJSON or FormatText.
+	Format string `yaml:"format" toml:"format"`
+}
+
+type TracingConfig struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterOTLP. With
+	// ExporterNone incoming trace context is still propagated, but no spans
+	// are recorded.
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the host:port of the OTLP/HTTP collector. When empty the
+	// OTEL_EXPORTER_OTLP_* environment variables or localhost:4318 are used.
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure sends spans to the collector over plain HTTP.
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+	// SampleRatio is the fraction of new traces that are recorded. Requests
+	// that carry a trace context follow the caller's sampling decision.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// API limits every request, per client IP address.
+	API RateLimitRule `yaml:"api" toml:"api"`
+	// Signup limits account creation, per client IP address.
+	Signup RateLimitRule `yaml:"signup" toml:"signup"`
+	// Login limits logins, per client IP address.
+	Login RateLimitRule `yaml:"login" toml:"login"`
+	// Messages limits sending channel and private messages, per user.
+	Messages RateLimitRule `yaml:"messages" toml:"messages"`
+}
+
+// RateLimitRule allows bursts of up to Requests requests, and Requests
+// requests every Per in the long run.
+type RateLimitRule struct {
+	Requests int           `yaml:"requests" toml:"requests"`
+	Per      time.Duration `yaml:"per" toml:"per"`
+}
+
+func (r RateLimitRule) String() string {
+	return fmt.Sprintf("%d/%s", r.Requests, r.Per)
+}
+
+// parseRateLimitRule parses a rule written as requests/period, such as
+// 10/1m.
+func parseRateLimitRule(s string) (RateLimitRule, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimitRule{}, fmt.Errorf("missing /")
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return RateLimitRule{}, err
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil {
+		return RateLimitRule{}, err
+	}
+	return RateLimitRule{Requests: n, Per: d}, nil
+}
+
+// Default returns the configuration used for settings that are not set
+// anywhere else.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver: DriverSQLite,
+			URL:    "chat.db",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL: 15 * time.Minute,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: FormatJSON,
+		},
+		Tracing: TracingConfig{
+			Exporter:    ExporterNone,
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			API:      RateLimitRule{Requests: 300, Per: time.Minute},
+			Signup:   RateLimitRule{Requests: 5, Per: time.Hour},
+			Login:    RateLimitRule{Requests: 10, Per: time.Minute},
+			Messages: RateLimitRule{Requests: 30, Per: time.Minute},
+		},
+	}
+}
+
+// Load reads the configuration. A missing .env file is ignored, but a
+// CONFIG_FILE that cannot be read is an error, as is any invalid setting.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile overlays the settings of a YAML or TOML file, chosen by its
+// extension, onto c.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overlays the settings found by lookup onto c.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if value, ok := lookup(name); ok && value != "" {
+			*dst = value
+		}
+	}
+	integer := func(name string, dst *int) {
+		if value, ok := lookup(name); ok && value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not an integer", name, value))
+				return
+			}
+			*dst = n
+		}
+	}
+	duration := func(name string, dst *time.Duration) {
+		if value, ok := lookup(name); ok && value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration such as 30s", name, value))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	boolean := func(name string, dst *bool) {
+		if value, ok := lookup(name); ok && value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a boolean", name, value))
+				return
+			}
+			*dst = b
+		}
+	}
+	float := func(name string, dst *float64) {
+		if value, ok := lookup(name); ok && value != "" {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, value))
+				return
+			}
+			*dst = f
+		}
+	}
+	rule := func(name string, dst *RateLimitRule) {
+		if value, ok := lookup(name); ok && value != "" {
+			r, err := parseRateLimitRule(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a rule such as 10/1m", name, value))
+				return
+			}
+			*dst = r
+		}
+	}
+
+	integer("PORT", &c.Server.Port)
+	duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	duration("HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	duration("SHUTDOWN_DELAY", &c.Server.ShutdownDelay)
+	str("DB_DRIVER", &c.Database.Driver)
+	str("DB_URL", &c.Database.URL)
+	str("AUTH_SECRET", &c.Auth.Secret)
+	duration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	str("LOG_LEVEL", &c.Logging.Level)
+	str("LOG_FORMAT", &c.Logging.Format)
+	str("TRACING_EXPORTER", &c.Tracing.Exporter)
+	str("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	boolean("TRACING_INSECURE", &c.Tracing.Insecure)
+	float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+	boolean("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	rule("RATE_LIMIT_API", &c.RateLimit.API)
+	rule("RATE_LIMIT_SIGNUP", &c.RateLimit.Signup)
+	rule("RATE_LIMIT_LOGIN", &c.RateLimit.Login)
+	rule("RATE_LIMIT_MESSAGES", &c.RateLimit.Messages)
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting of c.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %d is out of range", c.Server.Port))
+	}
+	for name, d := range map[string]time.Duration{
+		"server read timeout":     c.Server.ReadTimeout,
+		"server write timeout":    c.Server.WriteTimeout,
+		"server idle timeout":     c.Server.IdleTimeout,
+		"server shutdown timeout": c.Server.ShutdownTimeout,
+		"access token TTL":        c.Auth.AccessTokenTTL,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, fmt.Errorf("server shutdown delay must not be negative"))
+	}
+	if c.Server.ShutdownDelay >= c.Server.ShutdownTimeout {
+		errs = append(errs, fmt.Errorf("server shutdown delay must be shorter than the shutdown timeout"))
+	}
+	if c.Database.Driver != DriverSQLite && c.Database.Driver != DriverPostgres {
+		errs = append(errs, fmt.Errorf("database driver %q is not supported, use %s or %s", c.Database.Driver, DriverSQLite, DriverPostgres))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, fmt.Errorf("database URL is required"))
+	}
+	if _, err := c.Logging.ParseLevel(); err != nil {
+		errs = append(errs, fmt.Errorf("log level %q is not supported, use debug, info, warn or error", c.Logging.Level))
+	}
+	if c.Logging.Format != FormatJSON && c.Logging.Format != FormatText {
+		errs = append(errs, fmt.Errorf("log format %q is not supported, use %s or %s", c.Logging.Format, FormatJSON, FormatText))
+	}
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("trace exporter %q is not supported, use %s, %s or %s", c.Tracing.Exporter, ExporterNone, ExporterStdout, ExporterOTLP))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("trace sample ratio %g must be between 0 and 1", c.Tracing.SampleRatio))
+	}
+	if c.RateLimit.Enabled {
+		for name, r := range map[string]RateLimitRule{
+			"api":      c.RateLimit.API,
+			"signup":   c.RateLimit.Signup,
+			"login":    c.RateLimit.Login,
+			"messages": c.RateLimit.Messages,
+		} {
+			if r.Requests <= 0 || r.Per <= 0 {
+				errs = append(errs, fmt.Errorf("%s rate limit %s must allow a positive number of requests per positive period", name, r))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseLevel returns the slog level named by Level.
+func (c LoggingConfig) ParseLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.Level))
+	return level, err
+}
+
+const redacted = "REDACTED"
+
+// dsnPassword matches a password given as a key/value setting, such as
+// "host=db password=secret", or as a URL query parameter.
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|[^&\s]+)`)
+
+// Summary describes c for logging, one setting per line, with secrets
+// redacted.
+func (c *Config) Summary() string {
+	settings := c.settings()
+	lines := make([]string, len(settings))
+	for i, s := range settings {
+		lines[i] = s.key + " = " + s.value
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LogValue implements slog.LogValuer, logging the settings of c as a group
+// with secrets redacted.
+func (c *Config) LogValue() slog.Value {
+	settings := c.settings()
+	attrs := make([]slog.Attr, len(settings))
+	for i, s := range settings {
+		attrs[i] = slog.String(s.key, s.value)
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type setting struct {
+	key, value string
+}
+
+// settings lists every setting of c, with secrets redacted.
+func (c *Config) settings() []setting {
+	secret := "(random)"
+	if c.Auth.Secret != "" {
+		secret = redacted
+	}
+
+	return []setting{
+		{"server.port", strconv.Itoa(c.Server.Port)},
+		{"server.read_timeout", c.Server.ReadTimeout.String()},
+		{"server.write_timeout", c.Server.WriteTimeout.String()},
+		{"server.idle_timeout", c.Server.IdleTimeout.String()},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout.String()},
+		{"server.shutdown_delay", c.Server.ShutdownDelay.String()},
+		{"database.driver", c.Database.Driver},
+		{"database.url", redactURL(c.Database.URL)},
+		{"auth.secret", secret},
+		{"auth.access_token_ttl", c.Auth.AccessTokenTTL.String()},
+		{"logging.level", c.Logging.Level},
+		{"logging.format", c.Logging.Format},
+		{"tracing.exporter", c.Tracing.Exporter},
+		{"tracing.endpoint", c.Tracing.Endpoint},
+		{"tracing.insecure", strconv.FormatBool(c.Tracing.Insecure)},
+		{"tracing.sample_ratio", strconv.FormatFloat(c.Tracing.SampleRatio, 'g', -1, 64)},
+		{"rate_limit.enabled", strconv.FormatBool(c.RateLimit.Enabled)},
+		{"rate_limit.api", c.RateLimit.API.String()},
+		{"rate_limit.signup", c.RateLimit.Signup.String()},
+		{"rate_limit.login", c.RateLimit.Login.String()},
+		{"rate_limit.messages", c.RateLimit.Messages.String()},
+	}
+}
+
+// redactURL hides the password in a database URL or connection string.
+func redactURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			raw = u.String()
+		}
+	}
+	return dsnPassword.ReplaceAllString(raw, "${1}"+redacted)
+}
@@ -2,14 +2,14 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"strconv"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/config"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -20,42 +20,75 @@ type Service interface {
 	// The keys and values in the map are service-specific.
 	Health() map[string]string
 
+	// Ping verifies that the database is reachable.
+	Ping(ctx context.Context) error
+
+	// Stats returns the connection pool statistics.
+	Stats() sql.DBStats
+
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
 
-	// Migrate runs the database migrations.
+	// Migrate applies any pending migrations and, on SQLite, builds the
+	// message search index if it is missing.
 	// It returns an error if the migrations fail.
 	Migrate() error
 
+	// Migrator returns the migrator of the database, for finer control over
+	// migrations than Migrate offers.
+	Migrator() (Migrator, error)
+
 	// GetDB returns the database connection.
 	GetDB() *gorm.DB
 }
 
 type service struct {
-	db *gorm.DB
+	db     *gorm.DB
+	driver string
 }
 
-var (
-	dburl      = os.Getenv("DB_URL")
-	dbInstance *service
+// Supported database drivers.
+const (
+	DriverSQLite   = config.DriverSQLite
+	DriverPostgres = config.DriverPostgres
 )
 
-func New() Service {
-	// Reuse Connection
-	if dbInstance != nil {
-		return dbInstance
+// New connects to the configured database. Each call opens a new
+// connection pool, which the caller must close.
+func New(cfg config.DatabaseConfig) (Service, error) {
+	return Open(cfg.Driver, cfg.URL)
+}
+
+// Open connects to the database at dsn with the given driver. An empty
+// driver selects SQLite.
+func Open(driver, dsn string) (Service, error) {
+	if driver == "" {
+		driver = DriverSQLite
 	}
 
-	db, err := gorm.Open(sqlite.Open(dburl), &gorm.Config{})
-	if err != nil {
-		log.Fatal(err)
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
 	}
 
-	dbInstance = &service{
-		db: db,
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracingPlugin{}); err != nil {
+		return nil, err
 	}
-	return dbInstance
+
+	return &service{
+		db:     db,
+		driver: driver,
+	}, nil
 }
 
 func (s *service) GetDB() *gorm.DB {
@@ -65,29 +98,19 @@ func (s *service) GetDB() *gorm.DB {
 func (s *service) Health() map[string]string {
 	stats := make(map[string]string)
 
-	sqlDB, err := s.db.DB()
-	if err != nil {
-		stats["status"] = "down"
-		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err)
-		return stats
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	err = sqlDB.PingContext(ctx)
-	if err != nil {
+	if err := s.Ping(ctx); err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err)
 		return stats
 	}
 
 	stats["status"] = "up"
 	stats["message"] = "It's healthy"
 
-	dbStats := sqlDB.Stats()
+	dbStats := s.Stats()
 	stats["open_connections"] = strconv.Itoa(dbStats.OpenConnections)
 	stats["in_use"] = strconv.Itoa(dbStats.InUse)
 	stats["idle"] = strconv.Itoa(dbStats.Idle)
@@ -115,20 +138,49 @@ func (s *service) Health() map[string]string {
 	return stats
 }
 
+func (s *service) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *service) Stats() sql.DBStats {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
+}
+
 func (s *service) Close() error {
 	sqlDB, err := s.db.DB()
 	if err != nil {
 		return err
 	}
-	log.Printf("Disconnected from database: %s", dburl)
+	slog.Info("disconnected from database", "driver", s.driver)
 	return sqlDB.Close()
 }
 
 func (s *service) Migrate() error {
-	return s.db.AutoMigrate(
-		&model.User{},
-		&model.PrivateMessage{},
-		&model.Channel{},
-		&model.ChannelMessage{},
-	)
+	migrator, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		slog.Info("applied migration", "migration", migration.String())
+	}
+	if err != nil {
+		return err
+	}
+	if s.driver == DriverSQLite {
+		return setUpSQLiteSearch(s.db)
+	}
+	return nil
+}
+
+func (s *service) Migrator() (Migrator, error) {
+	return NewMigrator(s.db, s.driver)
 }
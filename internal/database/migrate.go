@@ -0,0 +1,320 @@
+package database
+
+import (
+	"cmp"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations live in migrations/<driver>/ as pairs of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, where version is a
+// zero-padded sequence number.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationName     = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// migrationLockKey identifies the PostgreSQL advisory lock held while
+// migrating.
+const migrationLockKey = 7_283_545_001
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus reports whether a migration has been applied. AppliedAt is
+// nil for pending migrations.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back the embedded migrations. Applied versions
+// are recorded in the schema_migrations table. Each run holds a database
+// lock for its duration, so instances starting together migrate one after
+// the other, and runs in a single transaction.
+type Migrator interface {
+	// Up applies all pending migrations in order and returns them.
+	Up() ([]Migration, error)
+
+	// Down rolls back the n most recently applied migrations and returns
+	// them in the order they were rolled back.
+	Down(n int) ([]Migration, error)
+
+	// Status lists every known migration, applied or pending, in order.
+	Status() ([]MigrationStatus, error)
+}
+
+type migrator struct {
+	db         *gorm.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for db using the embedded migrations of the
+// given driver.
+func NewMigrator(db *gorm.DB, driver string) (Migrator, error) {
+	sub, err := fs.Sub(migrationFiles, path.Join("migrations", driver))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(sub)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s migrations: %w", driver, err)
+	}
+	return &migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// loadMigrations reads the migrations in the root of fsys, ordered by
+// version. Every migration must have both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			return nil, fmt.Errorf("unexpected file %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("version %d is used by both %s and %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+func (m *migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func(tx *gorm.DB) error {
+		done, err := appliedVersions(tx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("applying %s: %w", migration, err)
+			}
+			err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now()).Error
+			if err != nil {
+				return fmt.Errorf("recording %s: %w", migration, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (m *migrator) Down(n int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(func(tx *gorm.DB) error {
+		var versions []int64
+		err := tx.Raw("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT ?", n).Scan(&versions).Error
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			i := slices.IndexFunc(m.migrations, func(m Migration) bool { return m.Version == version })
+			if i < 0 {
+				return fmt.Errorf("applied migration %d is unknown to this build", version)
+			}
+			migration := m.migrations[i]
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("rolling back %s: %w", migration, err)
+			}
+			if err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version).Error; err != nil {
+				return fmt.Errorf("recording rollback of %s: %w", migration, err)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rolledBack, nil
+}
+
+func (m *migrator) Status() ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(m.db); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations known to m that have not been applied, in
+// order.
+func Pending(m Migrator) ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// locked runs fn in a transaction on a single connection while holding the
+// migration lock. SQLite takes its database write lock up front; PostgreSQL
+// takes an advisory lock that is released when the transaction ends.
+func (m *migrator) locked(fn func(tx *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		begin := "BEGIN"
+		if m.driver == DriverSQLite {
+			begin = "BEGIN IMMEDIATE"
+		}
+		if err := conn.Exec(begin).Error; err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		if m.driver == DriverPostgres {
+			if err := conn.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				conn.Exec("ROLLBACK")
+				return fmt.Errorf("acquiring migration lock: %w", err)
+			}
+		}
+
+		err := ensureMigrationsTable(conn)
+		if err == nil {
+			err = fn(conn)
+		}
+		if err != nil {
+			conn.Exec("ROLLBACK")
+			return err
+		}
+		return conn.Exec("COMMIT").Error
+	})
+}
+
+func ensureMigrationsTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+}
+
+// appliedVersions returns the applied migration versions with the time they
+// were applied.
+func appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	var rows []struct {
+		Version   int64
+		AppliedAt time.Time
+	}
+	if err := db.Raw("SELECT version, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// CreateMigration adds empty up and down files for a new migration named
+// name to the migrations source directory dir, once per supported driver.
+// The version follows the highest one found in dir. It returns the paths
+// of the created files.
+func CreateMigration(dir, name string) ([]string, error) {
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	drivers := []string{DriverSQLite, DriverPostgres}
+	var next int64 = 1
+	for _, driver := range drivers {
+		migrations, err := loadMigrations(os.DirFS(filepath.Join(dir, driver)))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s migrations: %w", driver, err)
+		}
+		if len(migrations) > 0 {
+			next = max(next, migrations[len(migrations)-1].Version+1)
+		}
+	}
+
+	var created []string
+	for _, driver := range drivers {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, driver, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			content := fmt.Sprintf("-- %04d_%s (%s, %s)\n", next, name, driver, direction)
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+	migrations, err := loadMigrations(fstest.MapFS{
+		"0002_second.up.sql":   file("up 2"),
+		"0002_second.down.sql": file("down 2"),
+		"0001_first.up.sql":    file("up 1"),
+		"0001_first.down.sql":  file("down 1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].String() != "0001_first" || migrations[1].Down != "down 2" {
+		t.Errorf("unexpected migrations %+v", migrations)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing down":  {"0001_first.up.sql": file("up")},
+		"name clash":    {"0001_a.up.sql": file("up"), "0001_b.down.sql": file("down")},
+		"stray file":    {"README": file("")},
+		"bad file name": {"1-first.up.sql": file("up")},
+	} {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil || len(applied) == 0 {
+		t.Fatalf("expected migrations to be applied; got %v, %v", applied, err)
+	}
+	if applied, err := migrator.Up(); err != nil || len(applied) != 0 {
+		t.Errorf("expected nothing left to apply; got %v, %v", applied, err)
+	}
+	if !db.GetDB().Migrator().HasTable("users") {
+		t.Error("expected users table after migrating up")
+	}
+
+	rolledBack, err := migrator.Down(len(applied))
+	if err != nil || len(rolledBack) != len(applied) {
+		t.Fatalf("expected all migrations rolled back; got %v, %v", rolledBack, err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("expected %s to be pending", status.Migration)
+		}
+	}
+	if db.GetDB().Migrator().HasTable("users") {
+		t.Error("expected no users table after migrating down")
+	}
+}
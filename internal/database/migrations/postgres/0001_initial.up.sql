@@ -0,0 +1,115 @@
+-- Initial schema, matching what AutoMigrate created before versioned
+-- migrations were introduced. Statements are guarded with IF NOT EXISTS so
+-- that databases created by AutoMigrate are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    username text,
+    email text,
+    password_hash text,
+    is_admin boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (id)
+);
+DROP INDEX IF EXISTS idx_users_username;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_active ON users (username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS private_messages (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    sender_id text,
+    receiver_id text,
+    content text,
+    read_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_private_messages_sender_id ON private_messages (sender_id);
+CREATE INDEX IF NOT EXISTS idx_private_messages_receiver_id ON private_messages (receiver_id);
+CREATE INDEX IF NOT EXISTS idx_private_messages_deleted_at ON private_messages (deleted_at);
+
+CREATE TABLE IF NOT EXISTS channels (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    name text,
+    description text,
+    owner_id text,
+    visibility text NOT NULL DEFAULT 'public',
+    PRIMARY KEY (id)
+);
+DROP INDEX IF EXISTS idx_channels_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channels_name_active ON channels (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_channels_owner_id ON channels (owner_id);
+CREATE INDEX IF NOT EXISTS idx_channels_visibility ON channels (visibility);
+CREATE INDEX IF NOT EXISTS idx_channels_deleted_at ON channels (deleted_at);
+
+CREATE TABLE IF NOT EXISTS user_channels (
+    channel_id text,
+    user_id text,
+    role text NOT NULL DEFAULT 'member',
+    joined_at timestamptz,
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS channel_invites (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    channel_id text,
+    inviter_id text,
+    invitee_id text,
+    code text,
+    max_uses bigint,
+    uses bigint,
+    expires_at timestamptz,
+    status text NOT NULL DEFAULT 'pending',
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_invites_code ON channel_invites (code);
+CREATE INDEX IF NOT EXISTS idx_channel_invites_channel_id ON channel_invites (channel_id);
+CREATE INDEX IF NOT EXISTS idx_channel_invites_invitee_id ON channel_invites (invitee_id);
+CREATE INDEX IF NOT EXISTS idx_channel_invites_deleted_at ON channel_invites (deleted_at);
+
+CREATE TABLE IF NOT EXISTS channel_messages (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    channel_id text,
+    sender_id text,
+    content text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_channel_messages_channel_id ON channel_messages (channel_id);
+CREATE INDEX IF NOT EXISTS idx_channel_messages_sender_id ON channel_messages (sender_id);
+CREATE INDEX IF NOT EXISTS idx_channel_messages_deleted_at ON channel_messages (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    version bigint NOT NULL DEFAULT 1,
+    user_id text,
+    refresh_token_hash text,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_private_messages_search;
+DROP INDEX IF EXISTS idx_channel_messages_search;
@@ -0,0 +1,8 @@
+-- Full-text search of messages. Searches match to_tsvector('simple', content),
+-- so the same expression is indexed. The simple configuration neither stems
+-- nor drops stop words, which keeps search language-neutral.
+
+CREATE INDEX IF NOT EXISTS idx_channel_messages_search ON channel_messages
+    USING GIN (to_tsvector('simple', content)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_private_messages_search ON private_messages
+    USING GIN (to_tsvector('simple', content)) WHERE deleted_at IS NULL;
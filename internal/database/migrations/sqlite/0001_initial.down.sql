@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS channel_messages;
+DROP TABLE IF EXISTS channel_invites;
+DROP TABLE IF EXISTS user_channels;
+DROP TABLE IF EXISTS channels;
+DROP TABLE IF EXISTS private_messages;
+DROP TABLE IF EXISTS users;
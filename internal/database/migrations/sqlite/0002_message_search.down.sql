@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS private_messages_fts_delete;
+DROP TRIGGER IF EXISTS private_messages_fts_update;
+DROP TRIGGER IF EXISTS private_messages_fts_insert;
+DROP TRIGGER IF EXISTS channel_messages_fts_delete;
+DROP TRIGGER IF EXISTS channel_messages_fts_update;
+DROP TRIGGER IF EXISTS channel_messages_fts_insert;
+DROP TABLE IF EXISTS private_messages_fts;
+DROP TABLE IF EXISTS channel_messages_fts;
@@ -0,0 +1,5 @@
+-- The SQLite message search index needs FTS5, which is only available when
+-- the binary is built with the sqlite_fts5 tag, so it is built outside the
+-- versioned migrations; see setUpSQLiteSearch. This migration keeps the
+-- versions of both drivers aligned.
+SELECT 1;
@@ -0,0 +1,3 @@
+-- The original case of email addresses is not kept, so there is nothing to
+-- undo.
+SELECT 1;
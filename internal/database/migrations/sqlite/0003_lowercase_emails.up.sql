@@ -0,0 +1,5 @@
+-- Email addresses are compared in lower case. Addresses that would clash once
+-- lower-cased are left as they are.
+UPDATE users SET email = LOWER(email)
+WHERE email <> LOWER(email)
+	AND NOT EXISTS (SELECT 1 FROM users other WHERE other.id <> users.id AND LOWER(other.email) = LOWER(users.email));
@@ -0,0 +1,73 @@
+package database
+
+import (
+	_ "embed"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// sqliteSearchIndex creates and fills the SQLite message search index.
+//
+//go:embed search/sqlite.sql
+var sqliteSearchIndex string
+
+// sqliteSearchTables and sqliteSearchTriggers make up the SQLite message
+// search index.
+var (
+	sqliteSearchTables   = []string{"channel_messages_fts", "private_messages_fts"}
+	sqliteSearchTriggers = []string{
+		"channel_messages_fts_insert", "channel_messages_fts_update", "channel_messages_fts_delete",
+		"private_messages_fts_insert", "private_messages_fts_update", "private_messages_fts_delete",
+	}
+)
+
+// setUpSQLiteSearch builds the message search index of a SQLite database
+// unless it is complete already. The index needs FTS5, which go-sqlite3 only
+// compiles in with the sqlite_fts5 build tag; without it, search is left
+// unavailable rather than failing the migration.
+//
+// The index is not a versioned migration because it depends on how the
+// binary was built rather than on the schema. It is rebuilt from scratch
+// whenever a trigger is missing, as after the messages tables have been
+// dropped and created again.
+func setUpSQLiteSearch(db *gorm.DB) error {
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return fmt.Errorf("checking for FTS5: %w", err)
+	}
+	if !fts5 {
+		slog.Warn("message search is unavailable: SQLite was built without FTS5, build with -tags sqlite_fts5")
+		return nil
+	}
+
+	var triggers int64
+	err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", sqliteSearchTriggers).
+		Scan(&triggers).Error
+	if err != nil {
+		return fmt.Errorf("checking the search index: %w", err)
+	}
+	if triggers == int64(len(sqliteSearchTriggers)) {
+		return nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, trigger := range sqliteSearchTriggers {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+				return err
+			}
+		}
+		for _, table := range sqliteSearchTables {
+			if err := tx.Exec("DROP TABLE IF EXISTS " + table).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(sqliteSearchIndex).Error
+	})
+	if err != nil {
+		return fmt.Errorf("building the search index: %w", err)
+	}
+	slog.Info("built message search index")
+	return nil
+}
@@ -0,0 +1,57 @@
+-- Full-text search index of channel and private messages. Each index holds
+-- the content of the live (not soft-deleted) messages of its table, keyed by
+-- message_id, and is kept in sync by triggers.
+
+CREATE VIRTUAL TABLE channel_messages_fts USING fts5(
+    content,
+    message_id UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER channel_messages_fts_insert AFTER INSERT ON channel_messages
+WHEN new.deleted_at IS NULL
+BEGIN
+    INSERT INTO channel_messages_fts (content, message_id) VALUES (new.content, new.id);
+END;
+
+CREATE TRIGGER channel_messages_fts_update AFTER UPDATE OF content, deleted_at ON channel_messages
+BEGIN
+    DELETE FROM channel_messages_fts WHERE message_id = old.id;
+    INSERT INTO channel_messages_fts (content, message_id)
+        SELECT new.content, new.id WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER channel_messages_fts_delete AFTER DELETE ON channel_messages
+BEGIN
+    DELETE FROM channel_messages_fts WHERE message_id = old.id;
+END;
+
+INSERT INTO channel_messages_fts (content, message_id)
+    SELECT content, id FROM channel_messages WHERE deleted_at IS NULL;
+
+CREATE VIRTUAL TABLE private_messages_fts USING fts5(
+    content,
+    message_id UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER private_messages_fts_insert AFTER INSERT ON private_messages
+WHEN new.deleted_at IS NULL
+BEGIN
+    INSERT INTO private_messages_fts (content, message_id) VALUES (new.content, new.id);
+END;
+
+CREATE TRIGGER private_messages_fts_update AFTER UPDATE OF content, deleted_at ON private_messages
+BEGIN
+    DELETE FROM private_messages_fts WHERE message_id = old.id;
+    INSERT INTO private_messages_fts (content, message_id)
+        SELECT new.content, new.id WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER private_messages_fts_delete AFTER DELETE ON private_messages
+BEGIN
+    DELETE FROM private_messages_fts WHERE message_id = old.id;
+END;
+
+INSERT INTO private_messages_fts (content, message_id)
+    SELECT content, id FROM private_messages WHERE deleted_at IS NULL;
@@ -11,6 +11,9 @@ type BaseEntity struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	// Version is incremented by every update of the row and is used for
+	// optimistic concurrency control.
+	Version int64 `gorm:"not null;default:1" json:"version"`
 }
 
 func (e *BaseEntity) BeforeCreate(tx *gorm.DB) error {
@@ -20,6 +23,9 @@ func (e *BaseEntity) BeforeCreate(tx *gorm.DB) error {
 	now := time.Now()
 	e.CreatedAt = now
 	e.UpdatedAt = now
+	if e.Version == 0 {
+		e.Version = 1
+	}
 	return nil
 }
 
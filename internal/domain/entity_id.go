@@ -57,17 +57,26 @@ func (eid *EntityID) Scan(value interface{}) error {
 		return fmt.Errorf("cannot scan nil into EntityID")
 	}
 
+	var s string
 	switch v := value.(type) {
 	case []byte:
-		return eid.UnmarshalJSON(v)
+		s = string(v)
 	case string:
-		parsed, err := ParseEntityID(v)
-		if err != nil {
-			return err
-		}
-		*eid = parsed
-		return nil
+		s = v
 	default:
 		return fmt.Errorf("cannot scan %T into EntityID", value)
 	}
+
+	// Optional references, such as the invitee of an open invite, are stored
+	// as the zero EntityID.
+	if s == "" {
+		*eid = EntityID{}
+		return nil
+	}
+	parsed, err := ParseEntityID(s)
+	if err != nil {
+		return err
+	}
+	*eid = parsed
+	return nil
 }
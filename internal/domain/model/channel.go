@@ -1,10 +1,104 @@
 package model
 
-import "github.com/ruslanguns/go-chat/internal/domain"
+import (
+	"unicode/utf8"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+const maxChannelDescriptionLength = 500
+
+type ChannelVisibility string
+
+const (
+	// VisibilityPublic channels are listed for everyone and can be joined
+	// without an invitation.
+	VisibilityPublic ChannelVisibility = "public"
+	// VisibilityInviteOnly channels are listed for everyone but can only be
+	// joined with an invitation.
+	VisibilityInviteOnly ChannelVisibility = "invite_only"
+	// VisibilityPrivate channels are hidden from non-members and can only be
+	// joined with an invitation.
+	VisibilityPrivate ChannelVisibility = "private"
+)
+
+func (v ChannelVisibility) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityInviteOnly, VisibilityPrivate:
+		return true
+	}
+	return false
+}
 
 type Channel struct {
 	domain.BaseEntity
-	Name        string `gorm:"uniqueIndex" json:"name"`
-	Description string `json:"description"`
-	Users       []User `gorm:"many2many:user_channels;" json:"users"`
+	Name        string            `gorm:"uniqueIndex:idx_channels_name_active,where:deleted_at IS NULL" json:"name"`
+	Description string            `json:"description"`
+	OwnerID     domain.EntityID   `gorm:"index" json:"owner_id"`
+	Visibility  ChannelVisibility `gorm:"not null;default:public;index" json:"visibility"`
+}
+
+// NewChannel returns a channel owned by ownerID with a normalized name,
+// defaulting to public visibility.
+func NewChannel(ownerID domain.EntityID, name, description string, visibility ChannelVisibility) (*Channel, error) {
+	if visibility == "" {
+		visibility = VisibilityPublic
+	}
+
+	c := &Channel{
+		Name:        NormalizeChannelName(name),
+		Description: description,
+		OwnerID:     ownerID,
+		Visibility:  visibility,
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate checks the channel's fields, returning domain.FieldErrors listing
+// every invalid one.
+func (c *Channel) Validate() error {
+	var errs domain.FieldErrors
+	if err := ValidateChannelName(c.Name); err != nil {
+		errs = append(errs, domain.FieldError{Field: "name", Message: err.Error()})
+	}
+	if utf8.RuneCountInString(c.Description) > maxChannelDescriptionLength {
+		errs = append(errs, domain.FieldError{Field: "description", Message: "must be at most 500 characters"})
+	}
+	if !c.Visibility.IsValid() {
+		errs = append(errs, domain.FieldError{Field: "visibility", Message: "must be one of public, invite_only, private"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Channel) Rename(newName string) error {
+	newName = NormalizeChannelName(newName)
+	if err := ValidateChannelName(newName); err != nil {
+		return domain.FieldError{Field: "name", Message: err.Error()}
+	}
+	c.Name = newName
+	return nil
+}
+
+func (c *Channel) ChangeDescription(newDescription string) error {
+	if utf8.RuneCountInString(newDescription) > maxChannelDescriptionLength {
+		return domain.FieldError{Field: "description", Message: "must be at most 500 characters"}
+	}
+	c.Description = newDescription
+	return nil
+}
+
+func (c *Channel) ChangeVisibility(newVisibility ChannelVisibility) error {
+	if !newVisibility.IsValid() {
+		return domain.FieldError{Field: "visibility", Message: "must be one of public, invite_only, private"}
+	}
+	c.Visibility = newVisibility
+	return nil
 }
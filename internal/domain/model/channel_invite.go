@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+type InviteStatus string
+
+const (
+	InviteStatusPending  InviteStatus = "pending"
+	InviteStatusAccepted InviteStatus = "accepted"
+	InviteStatusDeclined InviteStatus = "declined"
+	InviteStatusRevoked  InviteStatus = "revoked"
+)
+
+// ChannelInvite grants access to a channel that cannot be joined freely.
+// An invite addressed to a user (InviteeID set) can only be used by them;
+// otherwise it is a shareable link usable by anyone holding the code, up to
+// MaxUses times (0 means unlimited).
+type ChannelInvite struct {
+	domain.BaseEntity
+	ChannelID domain.EntityID `gorm:"index" json:"channel_id"`
+	InviterID domain.EntityID `json:"inviter_id"`
+	InviteeID domain.EntityID `gorm:"index" json:"invitee_id"`
+	Code      string          `gorm:"uniqueIndex" json:"code"`
+	MaxUses   int             `json:"max_uses"`
+	Uses      int             `json:"uses"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+	Status    InviteStatus    `gorm:"not null;default:pending" json:"status"`
+}
+
+func (i *ChannelInvite) IsDirect() bool {
+	return !i.InviteeID.IsZero()
+}
+
+// IsUsable reports whether the invite can still be accepted.
+func (i *ChannelInvite) IsUsable(now time.Time) bool {
+	if i.Status != InviteStatusPending {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+type ChannelRole string
+
+const (
+	RoleOwner     ChannelRole = "owner"
+	RoleAdmin     ChannelRole = "admin"
+	RoleModerator ChannelRole = "moderator"
+	RoleMember    ChannelRole = "member"
+)
+
+var roleRanks = map[ChannelRole]int{
+	RoleMember:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+	RoleOwner:     4,
+}
+
+func (r ChannelRole) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r grants at least the privileges of other.
+func (r ChannelRole) AtLeast(other ChannelRole) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// Outranks reports whether r grants strictly more privileges than other.
+func (r ChannelRole) Outranks(other ChannelRole) bool {
+	return roleRanks[r] > roleRanks[other]
+}
+
+// ChannelMember is a user's membership of a channel.
+type ChannelMember struct {
+	ChannelID domain.EntityID `gorm:"primaryKey" json:"channel_id"`
+	UserID    domain.EntityID `gorm:"primaryKey" json:"user_id"`
+	Role      ChannelRole     `gorm:"not null;default:member" json:"role"`
+	JoinedAt  time.Time       `json:"joined_at"`
+}
+
+func (ChannelMember) TableName() string {
+	return "user_channels"
+}
+
+// ChannelUser is a user as listed among a channel's members.
+type ChannelUser struct {
+	User
+	Role     ChannelRole `json:"role"`
+	JoinedAt time.Time   `json:"joined_at"`
+}
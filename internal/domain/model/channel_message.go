@@ -1,12 +1,52 @@
 package model
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/ruslanguns/go-chat/internal/domain"
 )
 
 type ChannelMessage struct {
 	domain.BaseEntity
-	ChannelID domain.EntityID `json:"channel_id"`
-	SenderID  domain.EntityID `json:"sender_id"`
+	ChannelID domain.EntityID `gorm:"index" json:"channel_id"`
+	SenderID  domain.EntityID `gorm:"index" json:"sender_id"`
 	Content   string          `json:"content"`
 }
+
+func NewChannelMessage(channelID, senderID domain.EntityID, content string) (*ChannelMessage, error) {
+	m := &ChannelMessage{
+		BaseEntity: domain.BaseEntity{},
+		ChannelID:  channelID,
+		SenderID:   senderID,
+		Content:    strings.TrimSpace(content),
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *ChannelMessage) Validate() error {
+	if m.ChannelID.IsZero() {
+		return errors.New("channel id cannot be empty")
+	}
+	if m.SenderID.IsZero() {
+		return errors.New("sender id cannot be empty")
+	}
+	if err := ValidateMessageContent(m.Content); err != nil {
+		return domain.FieldError{Field: "content", Message: err.Error()}
+	}
+	return nil
+}
+
+func (m *ChannelMessage) ChangeContent(newContent string) error {
+	newContent = strings.TrimSpace(newContent)
+	if err := ValidateMessageContent(newContent); err != nil {
+		return domain.FieldError{Field: "content", Message: err.Error()}
+	}
+	m.Content = newContent
+	return nil
+}
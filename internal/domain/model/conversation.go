@@ -0,0 +1,11 @@
+package model
+
+import "github.com/ruslanguns/go-chat/internal/domain"
+
+// Conversation summarises the direct messages exchanged between a user and
+// one counterpart. It is built from private_messages and is not persisted.
+type Conversation struct {
+	CounterpartID domain.EntityID `json:"counterpart_id"`
+	LastMessage   *PrivateMessage `json:"last_message"`
+	UnreadCount   int64           `json:"unread_count"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+// Kinds of messages returned by a search.
+const (
+	MessageKindChannel = "channel"
+	MessageKindPrivate = "private"
+)
+
+// MessageSearch is a full-text search for messages, narrowed by optional
+// filters. Zero filters match everything.
+type MessageSearch struct {
+	// Text is the query: words, "quoted phrases", prefixes ending in * and
+	// OR between alternatives.
+	Text      string
+	ChannelID domain.EntityID
+	SenderID  domain.EntityID
+	// After and Before bound the time the messages were sent: After is
+	// inclusive, Before exclusive.
+	After  *time.Time
+	Before *time.Time
+	// HasLink keeps only messages containing an http or https link.
+	HasLink bool
+}
+
+// maxSearchTextLength bounds the length of a search query, in characters.
+const maxSearchTextLength = 256
+
+// Validate checks the search, returning domain.FieldErrors listing every
+// invalid field.
+func (s *MessageSearch) Validate() error {
+	var errs domain.FieldErrors
+	if text := strings.TrimSpace(s.Text); text == "" {
+		errs = append(errs, domain.FieldError{Field: "q", Message: "cannot be empty"})
+	} else if utf8.RuneCountInString(text) > maxSearchTextLength {
+		errs = append(errs, domain.FieldError{Field: "q", Message: "must be at most 256 characters"})
+	}
+	if s.After != nil && s.Before != nil && !s.After.Before(*s.Before) {
+		errs = append(errs, domain.FieldError{Field: "before", Message: "must be later than after"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MessageSearchHit is a message matching a search, either a channel message
+// (with ChannelID) or a private one (with ReceiverID). It is read from the
+// search index and is not persisted.
+type MessageSearchHit struct {
+	Kind       string           `json:"kind"`
+	ID         domain.EntityID  `json:"id"`
+	ChannelID  *domain.EntityID `json:"channel_id,omitempty"`
+	SenderID   domain.EntityID  `json:"sender_id"`
+	ReceiverID *domain.EntityID `json:"receiver_id,omitempty"`
+	Content    string           `json:"content"`
+	CreatedAt  time.Time        `json:"created_at"`
+	// Snippet is an HTML-escaped excerpt of Content with the matches wrapped
+	// in <mark> elements.
+	Snippet string `json:"snippet"`
+	// Score ranks the hit's relevance; higher is more relevant. Scores are
+	// only comparable within one search.
+	Score float64 `json:"score"`
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/ruslanguns/go-chat/internal/domain"
@@ -8,8 +10,43 @@ import (
 
 type PrivateMessage struct {
 	domain.BaseEntity
-	SenderID   domain.EntityID `json:"sender_id"`
-	ReceiverID domain.EntityID `json:"receiver_id"`
+	SenderID   domain.EntityID `gorm:"index" json:"sender_id"`
+	ReceiverID domain.EntityID `gorm:"index" json:"receiver_id"`
 	Content    string          `json:"content"`
 	ReadAt     *time.Time      `json:"read_at"`
 }
+
+func NewPrivateMessage(senderID, receiverID domain.EntityID, content string) (*PrivateMessage, error) {
+	m := &PrivateMessage{
+		BaseEntity: domain.BaseEntity{},
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    strings.TrimSpace(content),
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *PrivateMessage) Validate() error {
+	if m.SenderID.IsZero() {
+		return errors.New("sender id cannot be empty")
+	}
+	if m.ReceiverID.IsZero() {
+		return errors.New("receiver id cannot be empty")
+	}
+	if m.SenderID == m.ReceiverID {
+		return errors.New("cannot send a message to yourself")
+	}
+	if err := ValidateMessageContent(m.Content); err != nil {
+		return domain.FieldError{Field: "content", Message: err.Error()}
+	}
+	return nil
+}
+
+func (m *PrivateMessage) IsRead() bool {
+	return m.ReadAt != nil
+}
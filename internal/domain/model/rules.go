@@ -0,0 +1,93 @@
+package model
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxEmailLength       = 254
+	maxChannelNameLength = 64
+	maxMessageLength     = 4000
+)
+
+var (
+	usernamePattern    = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+	channelNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// NormalizeUsername returns the canonical form of a username. Usernames are
+// case-insensitive and stored in lower case.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ValidateUsername checks a normalized username: 3 to 32 lowercase letters,
+// digits or underscores.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("must be 3 to 32 characters of lowercase letters, digits or underscores")
+	}
+	return nil
+}
+
+// NormalizeEmail returns the canonical form of an email address. Email
+// addresses are case-insensitive and stored in lower case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks that email is a bare RFC 5322 address, without a
+// display name or angle brackets.
+func ValidateEmail(email string) error {
+	if len(email) > maxEmailLength {
+		return errors.New("must be at most 254 characters")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+// ValidatePassword checks the password strength rules.
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("must be at least 8 characters")
+	}
+	return nil
+}
+
+// NormalizeChannelName returns the canonical form of a channel name. Channel
+// names are case-insensitive and stored in lower case.
+func NormalizeChannelName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateChannelName checks that a normalized channel name is a slug:
+// lowercase letters and digits separated by single hyphens, at most 64
+// characters.
+func ValidateChannelName(name string) error {
+	if len(name) > maxChannelNameLength {
+		return errors.New("must be at most 64 characters")
+	}
+	if !channelNamePattern.MatchString(name) {
+		return errors.New("must contain only lowercase letters, digits and single hyphens between them")
+	}
+	return nil
+}
+
+// ValidateMessageContent checks the content of a channel or private message
+// after surrounding whitespace has been trimmed.
+func ValidateMessageContent(content string) error {
+	if content == "" {
+		return errors.New("is required")
+	}
+	if utf8.RuneCountInString(content) > maxMessageLength {
+		return errors.New("must be at most 4000 characters")
+	}
+	return nil
+}
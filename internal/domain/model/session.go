@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+// Session is a logged-in device. Access tokens reference the session so that
+// logging out revokes them; the refresh token is stored only as a hash.
+type Session struct {
+	domain.BaseEntity
+	UserID           domain.EntityID `gorm:"index" json:"user_id"`
+	RefreshTokenHash string          `gorm:"uniqueIndex" json:"-"`
+	ExpiresAt        time.Time       `json:"expires_at"`
+	RevokedAt        *time.Time      `json:"revoked_at"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) Revoke(now time.Time) {
+	if s.RevokedAt == nil {
+		s.RevokedAt = &now
+	}
+}
@@ -1,56 +1,107 @@
 package model
 
 import (
-	"errors"
-	"strings"
-
 	"github.com/ruslanguns/go-chat/internal/domain"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const minPasswordLength = 8
+
+// dummyPasswordHash is a bcrypt hash, of the cost passwords are hashed with,
+// that no password is expected to match.
+const dummyPasswordHash = "$2a$10$z00SWEJ4L7toYWILmA8aTeMl0rmLD6wtvy6pIoXxGJ0UvkX0EmTaq"
+
 type User struct {
 	domain.BaseEntity
-	Username string `gorm:"uniqueIndex" json:"username"`
-	Email    string `gorm:"uniqueIndex" json:"email"`
+	Username     string `gorm:"uniqueIndex:idx_users_username_active,where:deleted_at IS NULL" json:"username"`
+	Email        string `gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL" json:"email"`
+	PasswordHash string `json:"-"`
+	IsAdmin      bool   `gorm:"not null;default:false" json:"is_admin"`
 }
 
 func NewUser(username, email, password string) (*User, error) {
 	u := &User{
 		BaseEntity: domain.BaseEntity{},
-		Username:   strings.TrimSpace(username),
-		Email:      strings.TrimSpace(email),
+		Username:   NormalizeUsername(username),
+		Email:      NormalizeEmail(email),
 	}
 
+	var errs domain.FieldErrors
 	if err := u.Validate(); err != nil {
+		errs = append(errs, err.(domain.FieldErrors)...)
+	}
+	if err := ValidatePassword(password); err != nil {
+		errs = append(errs, domain.FieldError{Field: "password", Message: err.Error()})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if err := u.SetPassword(password); err != nil {
 		return nil, err
 	}
 
 	return u, nil
 }
 
+// Validate checks the user's fields, returning domain.FieldErrors listing
+// every invalid one.
 func (u *User) Validate() error {
-	if u.Username == "" {
-		return errors.New("username cannot be empty")
+	var errs domain.FieldErrors
+	if err := ValidateUsername(u.Username); err != nil {
+		errs = append(errs, domain.FieldError{Field: "username", Message: err.Error()})
 	}
-	if u.Email == "" {
-		return errors.New("email cannot be empty")
+	if err := ValidateEmail(u.Email); err != nil {
+		errs = append(errs, domain.FieldError{Field: "email", Message: err.Error()})
+	}
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
 func (u *User) ChangeEmail(newEmail string) error {
-	newEmail = strings.TrimSpace(newEmail)
-	if newEmail == "" {
-		return errors.New("email cannot be empty")
+	newEmail = NormalizeEmail(newEmail)
+	if err := ValidateEmail(newEmail); err != nil {
+		return domain.FieldError{Field: "email", Message: err.Error()}
 	}
 	u.Email = newEmail
 	return nil
 }
 
 func (u *User) ChangeUsername(newUsername string) error {
-	newUsername = strings.TrimSpace(newUsername)
-	if newUsername == "" {
-		return errors.New("username cannot be empty")
+	newUsername = NormalizeUsername(newUsername)
+	if err := ValidateUsername(newUsername); err != nil {
+		return domain.FieldError{Field: "username", Message: err.Error()}
 	}
 	u.Username = newUsername
 	return nil
 }
+
+// SetPassword replaces the user's password with a bcrypt hash of password.
+func (u *User) SetPassword(password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return domain.FieldError{Field: "password", Message: err.Error()}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckMissingUserPassword takes as long as CheckPassword, so that a failed
+// login for a user who does not exist cannot be told apart by its timing from
+// one for a user who does.
+func CheckMissingUserPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+}
+
+// CheckPassword reports whether password matches the stored hash.
+func (u *User) CheckPassword(password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
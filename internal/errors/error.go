@@ -10,14 +10,39 @@ var (
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrInternal      = errors.New("internal error")
 	ErrAlreadyExists = errors.New("already exists")
+	ErrConflict      = errors.New("conflict")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrValidation    = errors.New("validation failed")
+	// ErrPreconditionFailed reports a conditional write against a stale
+	// version of a resource.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired reports a write that must be conditional but
+	// was not.
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrRateLimited reports a client that has made too many requests.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable reports a feature that the server cannot provide in its
+	// current configuration.
+	ErrUnavailable = errors.New("unavailable")
 )
 
+// AppError is an error of a known kind (one of the Err* sentinels) with a
+// message that is safe to show to clients. Details carries optional
+// structured information for the client and Cause the underlying error, if
+// any, which is never exposed. Both the kind and the cause are reachable
+// through errors.Is and errors.As.
 type AppError struct {
-	Err error
-	Msg string
+	Err     error
+	Msg     string
+	Details any
+	Cause   error
 }
 
 func (e AppError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v: %v", e.Msg, e.Err, e.Cause)
+	}
 	return fmt.Sprintf("%s: %v", e.Msg, e.Err)
 }
 
@@ -25,6 +50,37 @@ func (e AppError) ErrorType() error {
 	return e.Err
 }
 
+func (e AppError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e AppError) WithDetails(details any) AppError {
+	e.Details = details
+	return e
+}
+
 func NewAppError(err error, msg string) AppError {
 	return AppError{Err: err, Msg: msg}
 }
+
+// Wrap returns an AppError of the given kind that records cause as the
+// underlying error.
+func Wrap(err error, msg string, cause error) AppError {
+	return AppError{Err: err, Msg: msg, Cause: cause}
+}
+
+// Is reports whether any error in err's tree matches target. It is the
+// standard library errors.Is, re-exported so callers need only this package.
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's tree that matches target. It is the
+// standard library errors.As, re-exported so callers need only this package.
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
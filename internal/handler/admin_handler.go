@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type AdminHandler struct {
+	adminService service.AdminService
+}
+
+func NewAdminHandler(adminService service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+	}
+}
+
+// parseDeletedScope reads the deleted query parameter, which defaults to
+// listing deleted rows only.
+func parseDeletedScope(r *http.Request) (repository.DeletedScope, error) {
+	scope := repository.DeletedScope(r.URL.Query().Get("deleted"))
+	if scope == "" {
+		return repository.OnlyDeleted, nil
+	}
+	if !scope.IsValid() {
+		return "", errors.NewAppError(errors.ErrInvalidInput, "Invalid deleted parameter").
+			WithDetails(domain.FieldErrors{{Field: "deleted", Message: "must be one of: only, include"}})
+	}
+	return scope, nil
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := parseDeletedScope(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	users, err := h.adminService.ListUsers(r.Context(), user, scope, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, users)
+}
+
+func (h *AdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	restored, err := h.adminService.RestoreUser(r.Context(), user, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, restored.Version)
+	json.NewEncoder(w).Encode(restored)
+}
+
+func (h *AdminHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	if err := h.adminService.PurgeUser(r.Context(), user, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := parseDeletedScope(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	channels, err := h.adminService.ListChannels(r.Context(), user, scope, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, channels)
+}
+
+func (h *AdminHandler) RestoreChannel(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	restored, err := h.adminService.RestoreChannel(r.Context(), user, channelID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, restored.Version)
+	json.NewEncoder(w).Encode(restored)
+}
+
+func (h *AdminHandler) PurgeChannel(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	if err := h.adminService.PurgeChannel(r.Context(), user, channelID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
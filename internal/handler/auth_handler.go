@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ruslanguns/go-chat/internal/auth"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type AuthHandler struct {
+	authService service.AuthService
+}
+
+func NewAuthHandler(authService service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+type loginRequest struct {
+	Username string `json:"username" validate:"required_without=Email"`
+	Email    string `json:"email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	login := req.Username
+	if login == "" {
+		login = req.Email
+	}
+
+	tokens, err := h.authService.Login(r.Context(), login, req.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := auth.SessionIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.NewAppError(errors.ErrUnauthorized, "Not authenticated"))
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), sessionID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// Authenticate resolves the bearer token on the request into the current
+// user and stores it in the request context, rejecting the request with 401
+// otherwise. Browsers cannot set headers on WebSocket or EventSource
+// connections, so the token may also be passed as the access_token query
+// parameter.
+func (h *AuthHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, errors.NewAppError(errors.ErrUnauthorized, "Missing access token"))
+			return
+		}
+
+		user, sessionID, err := h.authService.Authenticate(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user, sessionID)))
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("access_token")
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/realtime"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+// heartbeatInterval keeps idle event streams alive through proxies.
+const heartbeatInterval = 15 * time.Second
+
+type ChannelEventHandler struct {
+	stream         *realtime.Stream
+	channelService service.ChannelService
+}
+
+func NewChannelEventHandler(stream *realtime.Stream, channelService service.ChannelService) *ChannelEventHandler {
+	return &ChannelEventHandler{
+		stream:         stream,
+		channelService: channelService,
+	}
+}
+
+// Stream serves the channel's events as Server-Sent Events. The caller must
+// be a member of the channel. A reconnecting client resumes after the ID sent in the
+// Last-Event-ID header (or last_event_id query parameter); if events were
+// missed in between, a stream.reset event is sent first.
+func (h *ChannelEventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	isMember, err := h.channelService.IsChannelMember(r.Context(), channelID, user.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !isMember {
+		writeError(w, r, errors.NewAppError(errors.ErrForbidden, "Not a member of the channel"))
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var resumeFrom uint64
+	if lastEventID != "" {
+		resumeFrom, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid Last-Event-ID"))
+			return
+		}
+	}
+
+	// The server's WriteTimeout would otherwise cut the stream off.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, r, errors.Wrap(errors.ErrInternal, "Streaming unsupported", err))
+		return
+	}
+
+	sub, backlog, complete := h.stream.Subscribe(channelID, user.ID, resumeFrom)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		writeEvent(w, realtime.Event{Type: realtime.EventStreamReset, ChannelID: &channelID})
+	}
+	for _, event := range backlog {
+		writeEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event realtime.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if event.ID != 0 {
+		fmt.Fprintf(w, "id: %d\n", event.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
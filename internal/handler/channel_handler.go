@@ -3,12 +3,12 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ruslanguns/go-chat/internal/domain"
 	"github.com/ruslanguns/go-chat/internal/domain/model"
 	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"github.com/ruslanguns/go-chat/internal/service"
 )
 
@@ -22,16 +22,27 @@ func NewChannelHandler(channelService service.ChannelService) *ChannelHandler {
 	}
 }
 
+type channelRequest struct {
+	Name        string                  `json:"name" validate:"required,channel_name"`
+	Description string                  `json:"description" validate:"max=500"`
+	Visibility  model.ChannelVisibility `json:"visibility" validate:"omitempty,oneof=public invite_only private"`
+}
+
 func (h *ChannelHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var channel model.Channel
-	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	createdChannel, err := h.channelService.CreateChannel(channel.Name, channel.Description)
+	var req channelRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	createdChannel, err := h.channelService.CreateChannel(r.Context(), user, req.Name, req.Description, req.Visibility)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -40,19 +51,24 @@ func (h *ChannelHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChannelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
 		return
 	}
 
-	channel, err := h.channelService.GetChannelByID(channelID)
+	channel, err := h.channelService.GetChannelByID(r.Context(), user, channelID)
 	if err != nil {
-		if err, ok := err.(errors.AppError); ok && err.ErrorType() == errors.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	if notModified(w, r, channel.Version) {
 		return
 	}
 
@@ -60,37 +76,117 @@ func (h *ChannelHandler) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChannelHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
 		return
 	}
 
-	var channel model.Channel
-	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	channel.ID = channelID
 
-	if err := h.channelService.UpdateChannel(&channel); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var req channelRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	channel, err := h.channelService.UpdateChannel(r.Context(), user, channelID, version, req.Name, req.Description, req.Visibility)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, channel.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(channel)
 }
 
+type patchChannelRequest struct {
+	Name        *string                  `json:"name" validate:"omitnil,channel_name"`
+	Description *string                  `json:"description" validate:"omitnil,max=500"`
+	Visibility  *model.ChannelVisibility `json:"visibility" validate:"omitnil,oneof=public invite_only private"`
+}
+
+// Patch applies a JSON Merge Patch to the channel. Removing the description
+// clears it and removing the visibility resets it to public.
+func (h *ChannelHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req patchChannelRequest
+	nulls, err := decodeMergePatch(r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := rejectNulls(nulls, "name"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	patch := service.ChannelPatch{Name: req.Name, Description: req.Description, Visibility: req.Visibility}
+	if nulls["description"] {
+		empty := ""
+		patch.Description = &empty
+	}
+	if nulls["visibility"] {
+		public := model.VisibilityPublic
+		patch.Visibility = &public
+	}
+
+	channel, err := h.channelService.PatchChannel(r.Context(), user, channelID, version, patch)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, channel.Version)
+	json.NewEncoder(w).Encode(channel)
+}
+
 func (h *ChannelHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.channelService.DeleteChannel(channelID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.channelService.DeleteChannel(r.Context(), user, channelID, version); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -98,37 +194,70 @@ func (h *ChannelHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChannelHandler) List(w http.ResponseWriter, r *http.Request) {
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
 
-	if limit == 0 {
-		limit = 10 // default limit
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	channels, err := h.channelService.ListChannels(offset, limit)
+	channels, err := h.channelService.ListChannels(r.Context(), user, page)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(channels)
+	writePage(w, r, page, channels)
+}
+
+type addUserRequest struct {
+	UserID domain.EntityID `json:"user_id" validate:"required"`
 }
 
 func (h *ChannelHandler) AddUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	var req addUserRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.channelService.AddUserToChannel(r.Context(), user, channelID, req.UserID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChannelHandler) Join(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	var userID domain.EntityID
-	if err := json.NewDecoder(r.Body).Decode(&userID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
 		return
 	}
 
-	if err := h.channelService.AddUserToChannel(channelID, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.channelService.JoinChannel(r.Context(), user, channelID); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -136,20 +265,25 @@ func (h *ChannelHandler) AddUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChannelHandler) RemoveUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
 		return
 	}
 
 	userID, err := domain.ParseEntityID(chi.URLParam(r, "userId"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
 		return
 	}
 
-	if err := h.channelService.RemoveUserFromChannel(channelID, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.channelService.RemoveUserFromChannel(r.Context(), user, channelID, userID); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -157,24 +291,64 @@ func (h *ChannelHandler) RemoveUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChannelHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	users, err := h.channelService.GetChannelUsers(r.Context(), user, channelID, page)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	writePage(w, r, page, users)
+}
 
-	if limit == 0 {
-		limit = 10 // default limit
+type changeRoleRequest struct {
+	Role model.ChannelRole `json:"role" validate:"required,oneof=admin moderator member"`
+}
+
+func (h *ChannelHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
 	}
 
-	users, err := h.channelService.GetChannelUsers(channelID, offset, limit)
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
 		return
 	}
 
-	json.NewEncoder(w).Encode(users)
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	var req changeRoleRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.channelService.ChangeMemberRole(r.Context(), user, channelID, userID, req.Role); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
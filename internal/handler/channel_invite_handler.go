@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type ChannelInviteHandler struct {
+	inviteService service.ChannelInviteService
+}
+
+func NewChannelInviteHandler(inviteService service.ChannelInviteService) *ChannelInviteHandler {
+	return &ChannelInviteHandler{
+		inviteService: inviteService,
+	}
+}
+
+type createInviteRequest struct {
+	UserID    *domain.EntityID `json:"user_id"`
+	MaxUses   int              `json:"max_uses" validate:"gte=0,lte=1000"`
+	ExpiresIn int              `json:"expires_in" validate:"gte=0,lte=2592000"` // seconds
+}
+
+func (h *ChannelInviteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	var req createInviteRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var inviteeID domain.EntityID
+	if req.UserID != nil {
+		inviteeID = *req.UserID
+	}
+
+	invite, err := h.inviteService.CreateInvite(r.Context(), user, channelID, inviteeID, req.MaxUses, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (h *ChannelInviteHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	invites, err := h.inviteService.ListChannelInvites(r.Context(), user, channelID, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, invites)
+}
+
+func (h *ChannelInviteHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	inviteID, err := domain.ParseEntityID(chi.URLParam(r, "inviteId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid invite ID"))
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(r.Context(), user, channelID, inviteID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChannelInviteHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	invites, err := h.inviteService.ListPendingInvites(r.Context(), user, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, invites)
+}
+
+func (h *ChannelInviteHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channel, err := h.inviteService.AcceptInvite(r.Context(), user, chi.URLParam(r, "code"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(channel)
+}
+
+func (h *ChannelInviteHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.inviteService.DeclineInvite(r.Context(), user, chi.URLParam(r, "code")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
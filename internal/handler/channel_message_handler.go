@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type ChannelMessageHandler struct {
+	messageService service.ChannelMessageService
+}
+
+type messageRequest struct {
+	Content string `json:"content" validate:"required,content"`
+}
+
+func NewChannelMessageHandler(messageService service.ChannelMessageService) *ChannelMessageHandler {
+	return &ChannelMessageHandler{
+		messageService: messageService,
+	}
+}
+
+func (h *ChannelMessageHandler) Create(w http.ResponseWriter, r *http.Request) {
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req messageRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	createdMessage, err := h.messageService.SendMessage(r.Context(), user, channelID, req.Content)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdMessage)
+}
+
+func (h *ChannelMessageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	messageID, err := domain.ParseEntityID(chi.URLParam(r, "msgId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid message ID"))
+		return
+	}
+
+	message, err := h.messageService.GetMessage(r.Context(), user, channelID, messageID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if notModified(w, r, message.Version) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(message)
+}
+
+func (h *ChannelMessageHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	messageID, err := domain.ParseEntityID(chi.URLParam(r, "msgId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid message ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req messageRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	updatedMessage, err := h.messageService.UpdateMessage(r.Context(), user, channelID, messageID, version, req.Content)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, updatedMessage.Version)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedMessage)
+}
+
+func (h *ChannelMessageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	messageID, err := domain.ParseEntityID(chi.URLParam(r, "msgId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid message ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.messageService.DeleteMessage(r.Context(), user, channelID, messageID, version); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChannelMessageHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	channelID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid channel ID"))
+		return
+	}
+
+	page, err := parsePage(r, messagePageLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	messages, err := h.messageService.ListMessages(r.Context(), user, channelID, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, messages)
+}
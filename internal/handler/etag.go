@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+// etag returns the entity tag of a resource at the given version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// writeETag sets the ETag header for a resource at the given version.
+func writeETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// matches it, writes a 304 response and reports true.
+func notModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	writeETag(w, version)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion returns the version a write is conditional on, taken from
+// the request's If-Match header: service.AnyVersion for "*", otherwise the
+// version in the single entity tag. Writes to versioned resources must be
+// conditional, so a missing header is an error.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, errors.NewAppError(errors.ErrPreconditionRequired, "This request requires an If-Match header")
+	}
+	if header == "*" {
+		return service.AnyVersion, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version <= 0 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, errors.NewAppError(errors.ErrInvalidInput, "Invalid If-Match header")
+	}
+	return version, nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		kind    error
+	}{
+		{`"3"`, 3, nil},
+		{`*`, service.AnyVersion, nil},
+		{``, 0, errors.ErrPreconditionRequired},
+		{`3`, 0, errors.ErrInvalidInput},
+		{`W/"3"`, 0, errors.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+
+		version, err := ifMatchVersion(r)
+		if tt.kind != nil {
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("%q: expected %v; got %v", tt.header, tt.kind, err)
+			}
+			continue
+		}
+		if err != nil || version != tt.version {
+			t.Errorf("%q: expected version %d; got %d, %v", tt.header, tt.version, version, err)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"1", "2"`)
+
+	rec := httptest.NewRecorder()
+	if !notModified(rec, r, 2) || rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching tag; got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	if notModified(rec, r, 3) {
+		t.Error("expected stale tag not to match")
+	}
+	if rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("expected ETag header; got %q", rec.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+)
+
+// messagePageLimit is the default page size of message histories.
+const messagePageLimit = 50
+
+// parsePage reads the page of a list request from the cursor, offset and
+// limit query parameters. The limit defaults to defaultLimit and is capped at
+// pagination.MaxLimit; cursor and offset are exclusive.
+func parsePage(r *http.Request, defaultLimit int) (pagination.Page, error) {
+	query := r.URL.Query()
+	page := pagination.Page{Limit: defaultLimit}
+	var errs domain.FieldErrors
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			errs = append(errs, domain.FieldError{Field: "limit", Message: "must be a positive integer"})
+		}
+		page.Limit = min(limit, pagination.MaxLimit)
+	}
+
+	if s := query.Get("offset"); s != "" {
+		offset, err := strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			errs = append(errs, domain.FieldError{Field: "offset", Message: "must be a non-negative integer"})
+		}
+		page.Offset = offset
+	}
+
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := pagination.ParseCursor(s)
+		if err != nil {
+			errs = append(errs, domain.FieldError{Field: "cursor", Message: "is invalid"})
+		} else if query.Has("offset") {
+			errs = append(errs, domain.FieldError{Field: "cursor", Message: "cannot be combined with offset"})
+		}
+		page.After = &cursor
+	}
+
+	if len(errs) > 0 {
+		return page, errors.NewAppError(errors.ErrInvalidInput, "Invalid pagination parameters").WithDetails(errs)
+	}
+	return page, nil
+}
+
+// writePage writes a page of a list, linking to the next page in the Link
+// header when there is one.
+func writePage[T any](w http.ResponseWriter, r *http.Request, page pagination.Page, result *pagination.Result[T]) {
+	if result.HasMore {
+		next := *r.URL
+		query := next.Query()
+		query.Set("limit", strconv.Itoa(page.Limit))
+		if result.NextCursor != "" {
+			query.Del("offset")
+			query.Set("cursor", result.NextCursor)
+		} else {
+			query.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+		}
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
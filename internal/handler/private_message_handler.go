@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type PrivateMessageHandler struct {
+	messageService service.PrivateMessageService
+}
+
+func NewPrivateMessageHandler(messageService service.PrivateMessageService) *PrivateMessageHandler {
+	return &PrivateMessageHandler{
+		messageService: messageService,
+	}
+}
+
+func (h *PrivateMessageHandler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	conversations, err := h.messageService.ListConversations(r.Context(), user, userID, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, conversations)
+}
+
+func (h *PrivateMessageHandler) Send(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	counterpartID, err := domain.ParseEntityID(chi.URLParam(r, "counterpartId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid counterpart ID"))
+		return
+	}
+
+	var req messageRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	createdMessage, err := h.messageService.SendMessage(r.Context(), user, userID, counterpartID, req.Content)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdMessage)
+}
+
+func (h *PrivateMessageHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	counterpartID, err := domain.ParseEntityID(chi.URLParam(r, "counterpartId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid counterpart ID"))
+		return
+	}
+
+	page, err := parsePage(r, messagePageLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	messages, err := h.messageService.GetConversation(r.Context(), user, userID, counterpartID, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, messages)
+}
+
+func (h *PrivateMessageHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	counterpartID, err := domain.ParseEntityID(chi.URLParam(r, "counterpartId"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid counterpart ID"))
+		return
+	}
+
+	marked, err := h.messageService.MarkConversationRead(r.Context(), user, userID, counterpartID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]int64{"marked_read": marked})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/auth"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/logging"
+	"github.com/ruslanguns/go-chat/internal/ratelimit"
+)
+
+// RateLimitKey returns who a request is rate limited as.
+type RateLimitKey func(r *http.Request) string
+
+// ByIP rate limits requests by the address of the connecting client. Behind a
+// reverse proxy that is the proxy's address, so limits must then be enforced
+// by the proxy or keyed otherwise.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ByUser rate limits requests by the authenticated user, and requests without
+// one by IP address. It must run after Authenticate.
+func ByUser(r *http.Request) string {
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return "user:" + user.ID.String()
+	}
+	return ByIP(r)
+}
+
+// RateLimitPolicy limits the requests it is applied to. Each policy counts
+// requests separately, so a request subject to several policies takes a
+// token from each.
+type RateLimitPolicy struct {
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateLimitKey
+}
+
+// RateLimit rejects requests beyond policy with 429 Too Many Requests and a
+// Retry-After header. Every response carries the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset headers of the innermost
+// policy. Requests are let through if the store fails.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := policy.Name + ":" + policy.Key(r)
+			result, err := store.Take(r.Context(), key, policy.Limit, time.Now())
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("rate limit store failed", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ratelimit.RetryAfterSeconds(result.Reset)))
+			if !result.Allowed {
+				retryAfter := ratelimit.RetryAfterSeconds(result.RetryAfter)
+				h.Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(w, r, errors.NewAppError(errors.ErrRateLimited, "Too many requests").WithDetails(map[string]any{
+					"policy":      policy.Name,
+					"retry_after": retryAfter,
+				}))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
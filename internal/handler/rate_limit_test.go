@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, stderrors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	policy := RateLimitPolicy{Name: "test", Limit: ratelimit.Limit{Burst: 1, Per: time.Minute}, Key: ByIP}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(store ratelimit.Store, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		RateLimit(store, policy)(ok).ServeHTTP(rec, req)
+		return rec
+	}
+
+	store := ratelimit.NewMemoryStore()
+	if rec := serve(store, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first request through; got %d", rec.Code)
+	}
+	rec := serve(store, "192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected 429 retrying after 60s for the same address; got %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(store, "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("expected another address to have its own limit; got %d", rec.Code)
+	}
+
+	if rec := serve(failingStore{}, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("expected requests through when the store fails; got %d", rec.Code)
+	}
+}
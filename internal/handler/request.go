@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/validation"
+)
+
+// decodeRequest decodes the JSON request body into dst and validates it
+// against its `validate` tags.
+func decodeRequest(r *http.Request, dst any) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return invalidBody(err)
+	}
+	return validation.Struct(dst)
+}
+
+// decodeMergePatch decodes a JSON Merge Patch (RFC 7396) body into dst, a
+// struct of pointer fields, and validates it. Members absent from the patch
+// are left nil. Members set to null, which ask for the field to be removed,
+// are also left nil and returned by name so the caller can reset or reject
+// them.
+func decodeMergePatch(r *http.Request, dst any) (map[string]bool, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, invalidBody(err)
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, invalidBody(err)
+	}
+	nulls := make(map[string]bool)
+	for name, raw := range members {
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			nulls[name] = true
+		}
+	}
+
+	if err := json.Unmarshal(body, dst); err != nil {
+		return nil, invalidBody(err)
+	}
+	return nulls, validation.Struct(dst)
+}
+
+// rejectNulls reports a validation error for each of the required fields a
+// merge patch tried to remove.
+func rejectNulls(nulls map[string]bool, fields ...string) error {
+	var errs domain.FieldErrors
+	for _, field := range fields {
+		if nulls[field] {
+			errs = append(errs, domain.FieldError{Field: field, Message: "cannot be removed"})
+		}
+	}
+	if len(errs) > 0 {
+		return errors.NewAppError(errors.ErrValidation, "Validation failed").WithDetails(errs)
+	}
+	return nil
+}
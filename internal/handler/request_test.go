@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMergePatch(t *testing.T) {
+	body := `{"name": "general", "description": null}`
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+
+	var req patchChannelRequest
+	nulls, err := decodeMergePatch(r, &req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Name == nil || *req.Name != "general" {
+		t.Errorf("expected name to be set; got %v", req.Name)
+	}
+	if req.Description != nil || !nulls["description"] {
+		t.Errorf("expected description to be removed; got %v, nulls %v", req.Description, nulls)
+	}
+	if req.Visibility != nil || nulls["visibility"] {
+		t.Errorf("expected visibility to be absent; got %v, nulls %v", req.Visibility, nulls)
+	}
+	if err := rejectNulls(nulls, "description"); err == nil {
+		t.Error("expected removing description to be rejected")
+	}
+}
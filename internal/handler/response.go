@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ruslanguns/go-chat/internal/auth"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/logging"
+)
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details"`
+	RequestID string `json:"request_id"`
+}
+
+type errorKind struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorKinds maps each AppError kind to its HTTP status and error code.
+// Kinds not listed here are reported as internal errors.
+var errorKinds = []errorKind{
+	{errors.ErrNotFound, http.StatusNotFound, "not_found"},
+	{errors.ErrInvalidInput, http.StatusBadRequest, "invalid_input"},
+	{errors.ErrValidation, http.StatusUnprocessableEntity, "validation_failed"},
+	{errors.ErrAlreadyExists, http.StatusConflict, "already_exists"},
+	{errors.ErrConflict, http.StatusConflict, "conflict"},
+	{errors.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{errors.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{errors.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{errors.ErrPreconditionRequired, http.StatusPreconditionRequired, "precondition_required"},
+	{errors.ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+	{errors.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+	{errors.ErrInternal, http.StatusInternalServerError, "internal"},
+}
+
+// currentUser returns the authenticated caller, writing a 401 response when
+// the request carries none.
+func currentUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.NewAppError(errors.ErrUnauthorized, "Not authenticated"))
+		return nil, false
+	}
+	return user, true
+}
+
+// writeError writes err as a JSON error response. Only AppError messages are
+// shown to the client; any other error is reported as an internal error.
+// The error is logged with its underlying cause, which the client never sees:
+// server errors at error level, client errors at debug level.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := errorResponse{
+		Code:      "internal",
+		Message:   "An unexpected error occurred",
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	status := http.StatusInternalServerError
+
+	var appErr errors.AppError
+	if errors.As(err, &appErr) {
+		resp.Message = appErr.Msg
+		resp.Details = appErr.Details
+		for _, kind := range errorKinds {
+			if errors.Is(appErr.Err, kind.err) {
+				status, resp.Code = kind.status, kind.code
+				break
+			}
+		}
+	}
+
+	level := slog.LevelDebug
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logging.FromContext(r.Context()).LogAttrs(r.Context(), level, "request failed",
+		slog.Int("status", status),
+		slog.String("code", resp.Code),
+		slog.String("error", err.Error()),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// invalidBody reports a request body that could not be decoded.
+func invalidBody(err error) error {
+	return errors.Wrap(errors.ErrInvalidInput, "Invalid request body", err)
+}
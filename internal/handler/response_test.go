@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/logging"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"not found", errors.NewAppError(errors.ErrNotFound, "User not found"), http.StatusNotFound, "not_found", "User not found"},
+		{"invalid input", errors.NewAppError(errors.ErrInvalidInput, "Invalid user data"), http.StatusBadRequest, "invalid_input", "Invalid user data"},
+		{"already exists", errors.NewAppError(errors.ErrAlreadyExists, "Taken"), http.StatusConflict, "already_exists", "Taken"},
+		{"wrapped", fmt.Errorf("context: %w", errors.NewAppError(errors.ErrForbidden, "Nope")), http.StatusForbidden, "forbidden", "Nope"},
+		{"cause hidden", errors.Wrap(errors.ErrInternal, "Failed to get user", stderrors.New("disk on fire")), http.StatusInternalServerError, "internal", "Failed to get user"},
+		{"plain error", stderrors.New("boom"), http.StatusInternalServerError, "internal", "An unexpected error occurred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d; got %d", tt.status, rec.Code)
+			}
+			var body errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if body.Code != tt.code || body.Message != tt.message {
+				t.Errorf("expected %s %q; got %s %q", tt.code, tt.message, body.Code, body.Message)
+			}
+		})
+	}
+}
+
+func TestWriteErrorLogsCause(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(logging.NewContext(req.Context(), logger))
+
+	writeError(httptest.NewRecorder(), req, errors.Wrap(errors.ErrInternal, "Failed to get user", stderrors.New("disk on fire")))
+
+	if !strings.Contains(buf.String(), "disk on fire") {
+		t.Errorf("expected the cause to be logged; got %s", buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+// hasLinkToken, written anywhere in the query text, is the same as has=link.
+const hasLinkToken = "has:link"
+
+type SearchHandler struct {
+	searchService service.SearchService
+}
+
+func NewSearchHandler(searchService service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// parseMessageSearch reads a message search from the q, channel_id,
+// sender_id, after, before and has query parameters.
+func parseMessageSearch(r *http.Request) (model.MessageSearch, error) {
+	query := r.URL.Query()
+	var search model.MessageSearch
+	var errs domain.FieldErrors
+
+	var words []string
+	for _, word := range strings.Fields(query.Get("q")) {
+		if strings.EqualFold(word, hasLinkToken) {
+			search.HasLink = true
+			continue
+		}
+		words = append(words, word)
+	}
+	search.Text = strings.Join(words, " ")
+
+	for _, p := range []struct {
+		name string
+		dst  *domain.EntityID
+	}{{"channel_id", &search.ChannelID}, {"sender_id", &search.SenderID}} {
+		if s := query.Get(p.name); s != "" {
+			id, err := domain.ParseEntityID(s)
+			if err != nil {
+				errs = append(errs, domain.FieldError{Field: p.name, Message: "must be a valid ID"})
+			}
+			*p.dst = id
+		}
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"after", &search.After}, {"before", &search.Before}} {
+		if s := query.Get(p.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				errs = append(errs, domain.FieldError{Field: p.name, Message: "must be an RFC 3339 timestamp"})
+			}
+			*p.dst = &t
+		}
+	}
+
+	switch has := query.Get("has"); has {
+	case "":
+	case "link":
+		search.HasLink = true
+	default:
+		errs = append(errs, domain.FieldError{Field: "has", Message: "must be link"})
+	}
+
+	if len(errs) > 0 {
+		return search, errors.NewAppError(errors.ErrInvalidInput, "Invalid search parameters").WithDetails(errs)
+	}
+	return search, nil
+}
+
+func (h *SearchHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := parseMessageSearch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	hits, err := h.searchService.SearchMessages(r.Context(), user, search, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, page, hits)
+}
@@ -3,12 +3,11 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ruslanguns/go-chat/internal/domain"
-	"github.com/ruslanguns/go-chat/internal/domain/model"
 	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"github.com/ruslanguns/go-chat/internal/service"
 )
 
@@ -22,21 +21,27 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 	}
 }
 
+type createUserRequest struct {
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email5322"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type updateUserRequest struct {
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email5322"`
+}
+
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var user model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var req createUserRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	createdUser, err := h.userService.CreateUser(user.Username, user.Email)
+	createdUser, err := h.userService.CreateUser(r.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
-		appErr, ok := err.(errors.AppError)
-		if ok {
-			http.Error(w, appErr.Error(), http.StatusConflict)
-			return
-		}
-		http.Error(w, "An unexpected error occurred", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -47,17 +52,17 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(r.Context(), userID)
 	if err != nil {
-		if err, ok := err.(errors.AppError); ok && err.ErrorType() == errors.ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	if notModified(w, r, user.Version) {
 		return
 	}
 
@@ -65,37 +70,105 @@ func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	current, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	var user model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var req updateUserRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
-	user.ID = userID
 
-	if err := h.userService.UpdateUser(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	user, err := h.userService.UpdateUser(r.Context(), current, userID, version, req.Username, req.Email)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	writeETag(w, user.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
 
+type patchUserRequest struct {
+	Username *string `json:"username" validate:"omitnil,username"`
+	Email    *string `json:"email" validate:"omitnil,email5322"`
+}
+
+// Patch applies a JSON Merge Patch to the user.
+func (h *UserHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	current, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req patchUserRequest
+	nulls, err := decodeMergePatch(r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := rejectNulls(nulls, "username", "email"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.userService.PatchUser(r.Context(), current, userID, version, service.UserPatch{Username: req.Username, Email: req.Email})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeETag(w, user.Version)
+	json.NewEncoder(w).Encode(user)
+}
+
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	current, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
 	userID, err := domain.ParseEntityID(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, r, errors.NewAppError(errors.ErrInvalidInput, "Invalid user ID"))
 		return
 	}
 
-	if err := h.userService.DeleteUser(userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.userService.DeleteUser(r.Context(), current, userID, version); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -103,18 +176,17 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-
-	if limit == 0 {
-		limit = 10
+	page, err := parsePage(r, pagination.DefaultLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	users, err := h.userService.ListUsers(offset, limit)
+	users, err := h.userService.ListUsers(r.Context(), page)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(users)
+	writePage(w, r, page, users)
 }
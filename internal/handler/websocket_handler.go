@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/logging"
+	"github.com/ruslanguns/go-chat/internal/realtime"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+type WebSocketHandler struct {
+	hub            *realtime.Hub
+	channelService service.ChannelService
+	upgrader       websocket.Upgrader
+}
+
+func NewWebSocketHandler(hub *realtime.Hub, channelService service.ChannelService) *WebSocketHandler {
+	return &WebSocketHandler{
+		hub:            hub,
+		channelService: channelService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+// Connect upgrades an authenticated request to a websocket connection served
+// by the hub.
+func (h *WebSocketHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("websocket upgrade failed", "error", err)
+		return
+	}
+
+	// The connection outlives the request, whose context is cancelled once
+	// this handler returns; keep only its values, such as the trace.
+	ctx := context.WithoutCancel(r.Context())
+	h.hub.ServeClient(conn, user.ID, func(userID, channelID domain.EntityID) (bool, error) {
+		return h.channelService.IsChannelMember(ctx, channelID, userID)
+	})
+}
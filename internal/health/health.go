@@ -0,0 +1,144 @@
+// Package health runs the checks behind the liveness and readiness probes.
+// Checks are registered by name and run concurrently, each with its own
+// timeout; a failing check is reported, never fatal.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single check when the registry is given none.
+const DefaultTimeout = 2 * time.Second
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Checker reports whether a dependency is usable, returning an error
+// describing the problem when it is not.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    Status  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every registered check. It is up only when all
+// checks are.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry holds named checks.
+type Registry interface {
+	// Register adds a check, replacing any check with the same name.
+	Register(name string, checker Checker)
+
+	// Run runs every check concurrently and reports their outcome.
+	Run(ctx context.Context) Report
+}
+
+type registry struct {
+	mu      sync.RWMutex
+	checks  map[string]Checker
+	timeout time.Duration
+}
+
+// NewRegistry returns an empty registry whose checks each get timeout to
+// finish, or DefaultTimeout when timeout is not positive.
+func NewRegistry(timeout time.Duration) Registry {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &registry{
+		checks:  make(map[string]Checker),
+		timeout: timeout,
+	}
+}
+
+func (r *registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = checker
+}
+
+func (r *registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]Checker, len(r.checks))
+	for name, checker := range r.checks {
+		checks[name] = checker
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, checker := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := r.run(ctx, checker)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs a single check. A check that panics or outlives its timeout is
+// reported as down; one that is still running is abandoned.
+func (r *registry) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("ok", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := registry.Run(context.Background())
+	if report.Status != StatusUp || report.Checks["ok"].Status != StatusUp {
+		t.Fatalf("expected every check to be up; got %+v", report)
+	}
+
+	registry.Register("failing", CheckerFunc(func(ctx context.Context) error { return errors.New("broken") }))
+	registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	registry.Register("panicking", CheckerFunc(func(ctx context.Context) error { panic("boom") }))
+
+	start := time.Now()
+	report = registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the slow check to be cut off by its timeout; took %s", elapsed)
+	}
+	if report.Status != StatusDown {
+		t.Errorf("expected the report to be down; got %s", report.Status)
+	}
+	for name, want := range map[string]string{
+		"failing":   "broken",
+		"slow":      "check timed out: context deadline exceeded",
+		"panicking": "check panicked: boom",
+	} {
+		result := report.Checks[name]
+		if result.Status != StatusDown || result.Error != want {
+			t.Errorf("%s: expected down with %q; got %+v", name, want, result)
+		}
+	}
+	if result := report.Checks["ok"]; result.Status != StatusUp || result.Error != "" {
+		t.Errorf("expected the healthy check to stay up; got %+v", result)
+	}
+}
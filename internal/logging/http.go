@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID of a request, both from a caller that
+// assigned one and back to the client.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs accepted from callers, so that they cannot
+// inject arbitrary text into logs and responses.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// RequestID assigns each request an ID, reusing a valid X-Request-ID header
+// from the caller, and echoes it in the response's X-Request-ID header. The
+// ID is stored where middleware.GetReqID finds it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Middleware gives each request a logger tagged with its request ID and, when
+// the request is traced, its trace ID, and logs every request once it has been
+// served. It must run after RequestID and the tracing middleware.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLogger := logger.With("request_id", middleware.GetReqID(r.Context()))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				reqLogger = reqLogger.With("trace_id", sc.TraceID().String())
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r.WithContext(NewContext(r.Context(), reqLogger)))
+
+			// The pattern is only complete once routing has finished.
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				// See metrics.HTTPMiddleware.
+				status = http.StatusOK
+				if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+					status = http.StatusSwitchingProtocols
+				}
+			}
+
+			reqLogger.LogAttrs(r.Context(), slog.LevelInfo, "request served",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
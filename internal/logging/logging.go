@@ -0,0 +1,42 @@
+// Package logging sets up structured logging with log/slog and carries a
+// request-scoped logger, tagged with the request ID, in the context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+)
+
+// New returns a logger writing to w at the level and in the format given by
+// cfg. cfg is expected to be valid; an unknown level logs at info.
+func New(w io.Writer, cfg config.LoggingConfig) *slog.Logger {
+	level, err := cfg.ParseLevel()
+	if err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	if cfg.Format == config.FormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger when
+// there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
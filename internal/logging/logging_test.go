@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ruslanguns/go-chat/internal/config"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetReqID(r.Context())
+	}))
+
+	tests := []struct {
+		name, header string
+		reused       bool
+	}{
+		{"generated", "", false},
+		{"reused", "client-abc.123", true},
+		{"invalid", "bad id\nwith newline", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			if echoed == "" || echoed != seen {
+				t.Errorf("expected the response header %q to match the request's ID %q", echoed, seen)
+			}
+			if reused := seen == tt.header; reused != tt.reused {
+				t.Errorf("expected reused %t; got ID %q for header %q", tt.reused, seen, tt.header)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{Level: "debug", Format: config.FormatJSON})
+
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(Middleware(logger))
+	r.Get("/channels/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Debug("handling")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/channels/42", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var lines []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("expected JSON log lines; got %s", line)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected a line from the handler and one for the request; got %d", len(lines))
+	}
+	for _, entry := range lines {
+		if entry["request_id"] != "req-1" {
+			t.Errorf("expected every line to carry the request ID; got %v", entry)
+		}
+	}
+	served := lines[1]
+	if served["route"] != "/channels/{id}" || served["status"] != float64(http.StatusNotFound) {
+		t.Errorf("unexpected request log %v", served)
+	}
+}
+
+func TestFromContextDefaultsToDefaultLogger(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("expected the default logger for a context without one")
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths
+// cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// HTTPMiddleware records the count, latency and concurrency of requests,
+// labelled by method and chi route pattern.
+func HTTPMiddleware(reg Registry) func(http.Handler) http.Handler {
+	requests := reg.Counter("http_requests_total",
+		"HTTP requests served, by method, route pattern and status code.",
+		"method", "route", "status")
+	duration := reg.Histogram("http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by method and route pattern.",
+		nil, "method", "route")
+	inFlight := reg.Gauge("http_requests_in_flight",
+		"HTTP requests currently being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			// The pattern is only complete once routing has finished.
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				// Nothing was written through the wrapper: either the handler
+				// wrote no response, which net/http sends as 200, or it
+				// hijacked the connection for a websocket upgrade.
+				status = http.StatusOK
+				if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+					status = http.StatusSwitchingProtocols
+				}
+			}
+
+			requests.Inc(r.Method, route, strconv.Itoa(status))
+			duration.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Package metrics records application metrics and exposes them in the
+// Prometheus text format. Code records metrics through the small Registry
+// interface rather than the Prometheus client, so any package can register
+// its own metrics without depending on how they are exported.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the name of every metric registered through a Registry.
+const Namespace = "chat"
+
+// Counter is a value that only goes up. Label values are given in the order
+// the labels were declared.
+type Counter interface {
+	Inc(labelValues ...string)
+	Add(value float64, labelValues ...string)
+}
+
+// Gauge is a value that goes up and down.
+type Gauge interface {
+	Set(value float64, labelValues ...string)
+	Inc(labelValues ...string)
+	Dec(labelValues ...string)
+}
+
+// Histogram counts observations into buckets.
+type Histogram interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// Registry creates metrics and serves them. Registering a metric under a name
+// that is already taken by the same kind of metric returns the existing one,
+// so several components can share a metric by registering it alike.
+type Registry interface {
+	Counter(name, help string, labels ...string) Counter
+	Gauge(name, help string, labels ...string) Gauge
+	// Histogram uses prometheus.DefBuckets when buckets is nil.
+	Histogram(name, help string, buckets []float64, labels ...string) Histogram
+
+	// CounterFunc and GaugeFunc register metrics whose value is read from fn
+	// at collection time.
+	CounterFunc(name, help string, fn func() float64)
+	GaugeFunc(name, help string, fn func() float64)
+
+	// Handler serves the registered metrics.
+	Handler() http.Handler
+}
+
+type registry struct {
+	reg *prometheus.Registry
+}
+
+// NewRegistry returns a registry that already holds the Go runtime and
+// process metrics.
+func NewRegistry() Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return &registry{reg: reg}
+}
+
+func (r *registry) Counter(name, help string, labels ...string) Counter {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: Namespace, Name: name, Help: help}, labels)
+	return counter{register(r.reg, vec)}
+}
+
+func (r *registry) Gauge(name, help string, labels ...string) Gauge {
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: Namespace, Name: name, Help: help}, labels)
+	return gauge{register(r.reg, vec)}
+}
+
+func (r *registry) Histogram(name, help string, buckets []float64, labels ...string) Histogram {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: Namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	return histogram{register(r.reg, vec)}
+}
+
+func (r *registry) CounterFunc(name, help string, fn func() float64) {
+	register(r.reg, prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: Namespace, Name: name, Help: help}, fn))
+}
+
+func (r *registry) GaugeFunc(name, help string, fn func() float64) {
+	register(r.reg, prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: Namespace, Name: name, Help: help}, fn))
+}
+
+func (r *registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg})
+}
+
+// register registers c, returning the collector already registered in its
+// place if there is one. It panics if the name is taken by a different kind
+// of metric or with different labels, which is a programming error.
+func register[C prometheus.Collector](reg *prometheus.Registry, c C) C {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing
+		}
+	}
+	panic(err)
+}
+
+type counter struct{ vec *prometheus.CounterVec }
+
+func (c counter) Inc(labelValues ...string) { c.vec.WithLabelValues(labelValues...).Inc() }
+func (c counter) Add(value float64, labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Add(value)
+}
+
+type gauge struct{ vec *prometheus.GaugeVec }
+
+func (g gauge) Set(value float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Set(value)
+}
+func (g gauge) Inc(labelValues ...string) { g.vec.WithLabelValues(labelValues...).Inc() }
+func (g gauge) Dec(labelValues ...string) { g.vec.WithLabelValues(labelValues...).Dec() }
+
+type histogram struct{ vec *prometheus.HistogramVec }
+
+func (h histogram) Observe(value float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(value)
+}
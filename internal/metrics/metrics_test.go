@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T, reg Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestRegistrySharesMetrics(t *testing.T) {
+	reg := NewRegistry()
+	first := reg.Counter("things_total", "Things.", "kind")
+	second := reg.Counter("things_total", "Things.", "kind")
+	first.Inc("a")
+	second.Add(2, "a")
+	reg.GaugeFunc("answer", "The answer.", func() float64 { return 42 })
+
+	body := scrape(t, reg)
+	for _, want := range []string{
+		`chat_things_total{kind="a"} 3`,
+		`chat_answer 42`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a name with other labels to panic")
+		}
+	}()
+	reg.Counter("things_total", "Things.", "color")
+}
+
+func TestHTTPMiddlewareLabelsRoutePatterns(t *testing.T) {
+	reg := NewRegistry()
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware(reg))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, reg)
+	for _, want := range []string{
+		`chat_http_requests_total{method="GET",route="/users/{id}",status="418"} 2`,
+		`chat_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`chat_http_request_duration_seconds_count{method="GET",route="/users/{id}"} 2`,
+		`chat_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package pagination describes pages of list results. Lists are paged with
+// opaque cursors keyed on a row's creation time and ID; offset paging is
+// kept for backwards compatibility.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// Cursor identifies the last row of a page; the next page starts right after
+// it in the list's order.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// String encodes the cursor into the opaque token handed to clients.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token produced by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.ID == "" || c.CreatedAt.IsZero() {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// Page selects a page of a list: the rows after After when it is set,
+// otherwise the rows from Offset on, at most Limit of them.
+type Page struct {
+	After  *Cursor
+	Offset int
+	Limit  int
+}
+
+// Result is one page of a list.
+type Result[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NewResult builds the result for page from items, which must have been
+// fetched with a limit of page.Limit+1 so that the extra row reveals whether
+// more follow. cursorOf returns the cursor of an item; when it is nil the
+// list can only be paged by offset and no cursor is returned.
+func NewResult[T any](items []T, page Page, cursorOf func(T) Cursor) *Result[T] {
+	result := &Result[T]{Items: items}
+	if len(items) > page.Limit {
+		result.Items = items[:page.Limit]
+		result.HasMore = true
+	}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	if result.HasMore && cursorOf != nil && len(result.Items) > 0 {
+		result.NextCursor = cursorOf(result.Items[len(result.Items)-1]).String()
+	}
+	return result
+}
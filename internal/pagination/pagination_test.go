@@ -0,0 +1,47 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC), ID: "abc"}
+
+	parsed, err := ParseCursor(c.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !parsed.CreatedAt.Equal(c.CreatedAt) || parsed.ID != c.ID {
+		t.Errorf("expected %v; got %v", c, parsed)
+	}
+
+	for _, s := range []string{"", "not base64!", "e30"} {
+		if _, err := ParseCursor(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	cursorOf := func(n int) Cursor { return Cursor{CreatedAt: time.Unix(int64(n), 0), ID: "id"} }
+	page := Page{Limit: 2}
+
+	result := NewResult([]int{1, 2, 3}, page, cursorOf)
+	if len(result.Items) != 2 || !result.HasMore {
+		t.Fatalf("expected 2 items and more; got %+v", result)
+	}
+	if next, err := ParseCursor(result.NextCursor); err != nil || !next.CreatedAt.Equal(time.Unix(2, 0)) {
+		t.Errorf("expected cursor of last item; got %v, %v", next, err)
+	}
+
+	result = NewResult([]int{1, 2}, page, cursorOf)
+	if result.HasMore || result.NextCursor != "" {
+		t.Errorf("expected last page; got %+v", result)
+	}
+
+	result = NewResult[int](nil, page, nil)
+	if result.Items == nil {
+		t.Error("expected empty items to encode as an empty list")
+	}
+}
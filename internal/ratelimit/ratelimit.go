@@ -0,0 +1,88 @@
+// Package ratelimit implements token bucket rate limiting. A Limit describes
+// a bucket and a Store keeps one bucket per key, such as a user or an IP
+// address.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket of Burst tokens refilled evenly over Per: a key may
+// make Burst requests at once, and Burst requests every Per in the long run.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// interval is the time it takes to refill one token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Burst)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	// Allowed reports whether a token was available.
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until a token is available again. It is zero
+	// when Remaining is positive.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the buckets of rate limited keys. Implementations must be safe
+// for concurrent use; a store shared by several servers enforces a limit
+// across all of them.
+type Store interface {
+	// Take takes a token from the bucket of key, created full with the given
+	// limit if it does not exist, as of now.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// bucket is the state of a token bucket, kept as the time at which it is
+// full again. Storing a single timestamp rather than a token count and a
+// refill time keeps updates to a single value.
+type bucket struct {
+	full time.Time
+}
+
+// take takes a token from b as of now, returning the updated bucket.
+func (b bucket) take(limit Limit, now time.Time) (bucket, Result) {
+	interval := limit.interval()
+	capacity := limit.Per
+
+	// The bucket never holds more than its capacity.
+	full := b.full
+	if full.Before(now) {
+		full = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	next := full.Add(interval)
+	if next.Sub(now) > capacity {
+		// Taking a token would overdraw the bucket.
+		result.RetryAfter = next.Sub(now) - capacity
+		result.Reset = full.Sub(now)
+		return bucket{full: full}, result
+	}
+
+	result.Allowed = true
+	result.Reset = next.Sub(now)
+	result.Remaining = int((capacity - result.Reset) / interval)
+	if result.Remaining == 0 {
+		result.RetryAfter = result.Reset - capacity + interval
+	}
+	return bucket{full: next}, result
+}
+
+// RetryAfterSeconds rounds d up to whole seconds, as used by the Retry-After
+// header.
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
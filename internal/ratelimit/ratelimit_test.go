@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 3, Per: 3 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	take := func(key string) Result {
+		t.Helper()
+		result, err := store.Take(context.Background(), key, limit, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for want := 2; want >= 0; want-- {
+		result := take("alice")
+		if !result.Allowed || result.Remaining != want || result.Limit != 3 {
+			t.Fatalf("expected an allowed request with %d remaining; got %+v", want, result)
+		}
+	}
+	if result := take("alice"); result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("expected the burst to be exhausted, retrying after 1s; got %+v", result)
+	}
+	if result := take("bob"); !result.Allowed {
+		t.Errorf("expected keys to have their own buckets; got %+v", result)
+	}
+
+	// Tokens are refilled one per second, and never beyond the burst.
+	now = now.Add(time.Second)
+	if result := take("alice"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected a refilled token; got %+v", result)
+	}
+	now = now.Add(time.Hour)
+	if result := take("alice"); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("expected a full bucket; got %+v", result)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 10, Per: time.Second}
+	now := time.Now()
+
+	store.Take(context.Background(), "alice", limit, now)
+	store.Take(context.Background(), "bob", limit, now.Add(2*sweepInterval))
+	if store.Len() != 1 {
+		t.Errorf("expected the idle bucket to be dropped; got %d buckets", store.Len())
+	}
+}
@@ -0,0 +1,185 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// pongWait is the time allowed to read the next pong from the peer.
+	pongWait = 60 * time.Second
+
+	// pingPeriod must be shorter than pongWait so the peer has time to reply.
+	pingPeriod = (pongWait * 9) / 10
+
+	// maxMessageSize bounds the size of commands accepted from the peer.
+	maxMessageSize = 4096
+
+	// sendBufferSize is how many outgoing events may queue up for a client
+	// before it is treated as a slow consumer and disconnected.
+	sendBufferSize = 256
+)
+
+// SubscribeAuthorizer reports whether a user may receive events for a channel.
+type SubscribeAuthorizer func(userID, channelID domain.EntityID) (bool, error)
+
+// Client is a single websocket connection belonging to an authenticated user.
+type Client struct {
+	hub       *Hub
+	conn      *websocket.Conn
+	userID    domain.EntityID
+	authorize SubscribeAuthorizer
+
+	send     chan []byte
+	mu       sync.Mutex
+	closed   bool
+	channels map[domain.EntityID]struct{}
+}
+
+// ServeClient registers the connection with the hub and starts its read and
+// write goroutines. It returns immediately; the connection is closed once the
+// peer goes away or the hub drops it, or right away if the hub is closed.
+func (h *Hub) ServeClient(conn *websocket.Conn, userID domain.EntityID, authorize SubscribeAuthorizer) {
+	c := &Client{
+		hub:       h,
+		conn:      conn,
+		userID:    userID,
+		authorize: authorize,
+		send:      make(chan []byte, sendBufferSize),
+		channels:  make(map[domain.EntityID]struct{}),
+	}
+	if !h.register(c) {
+		conn.Close()
+		return
+	}
+
+	go c.writePump()
+	go c.readPump()
+}
+
+// enqueue queues payload for delivery. It reports false when the send buffer
+// is full.
+func (c *Client) enqueue(payload []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return true
+	}
+	select {
+	case c.send <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				slog.Warn("realtime: read error", "user_id", c.userID.String(), "error", err)
+			}
+			return
+		}
+		c.handleCommand(data)
+	}
+}
+
+func (c *Client) handleCommand(data []byte) {
+	var cmd command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		c.reply(reply{Type: "error", Error: "invalid command"})
+		return
+	}
+
+	switch cmd.Type {
+	case commandSubscribe:
+		allowed, err := c.authorize(c.userID, cmd.ChannelID)
+		if err != nil {
+			c.reply(reply{Type: "error", ChannelID: cmd.ChannelID, Error: "failed to subscribe"})
+			return
+		}
+		if !allowed {
+			c.reply(reply{Type: "error", ChannelID: cmd.ChannelID, Error: "not a member of the channel"})
+			return
+		}
+		c.hub.subscribe(c, cmd.ChannelID)
+		c.reply(reply{Type: "subscribed", ChannelID: cmd.ChannelID})
+	case commandUnsubscribe:
+		c.hub.unsubscribe(c, cmd.ChannelID)
+		c.reply(reply{Type: "unsubscribed", ChannelID: cmd.ChannelID})
+	default:
+		c.reply(reply{Type: "error", Error: "unknown command"})
+	}
+}
+
+func (c *Client) reply(r reply) {
+	payload, err := encode(r)
+	if err != nil {
+		return
+	}
+	if !c.enqueue(payload) {
+		c.hub.unregister(c)
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		c.hub.writers.Done()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package realtime
+
+import (
+	"encoding/json"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+const (
+	EventChannelMessageCreated = "channel_message.created"
+	EventChannelMessageUpdated = "channel_message.updated"
+	EventChannelMessageDeleted = "channel_message.deleted"
+	EventChannelMemberAdded    = "channel.member_added"
+	EventChannelMemberRemoved  = "channel.member_removed"
+	EventChannelMemberRole     = "channel.member_role_changed"
+	EventPrivateMessageCreated = "private_message.created"
+
+	// EventStreamReset tells a resuming client that events were missed and
+	// it should reload state through the REST API.
+	EventStreamReset = "stream.reset"
+)
+
+// Event is the envelope pushed to connected clients. Channel events carry an
+// ID that increases monotonically and can be used to resume a stream.
+type Event struct {
+	ID        uint64           `json:"id,omitempty"`
+	Type      string           `json:"type"`
+	ChannelID *domain.EntityID `json:"channel_id,omitempty"`
+	Data      interface{}      `json:"data"`
+}
+
+type messageDeleted struct {
+	ID domain.EntityID `json:"id"`
+}
+
+type membershipChanged struct {
+	UserID domain.EntityID `json:"user_id"`
+	Role   string          `json:"role,omitempty"`
+}
+
+// command is a message sent by a client over its connection.
+type command struct {
+	Type      string          `json:"type"`
+	ChannelID domain.EntityID `json:"channel_id"`
+}
+
+const (
+	commandSubscribe   = "subscribe"
+	commandUnsubscribe = "unsubscribe"
+)
+
+// reply acknowledges or rejects a client command.
+type reply struct {
+	Type      string          `json:"type"`
+	ChannelID domain.EntityID `json:"channel_id"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
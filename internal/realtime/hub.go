@@ -0,0 +1,232 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+// Hub tracks connected clients and fans events out to them. Each client owns
+// a buffered send queue drained by its own write goroutine; a client whose
+// queue is full is considered too slow and is disconnected rather than
+// blocking delivery to everyone else.
+type Hub struct {
+	mu       sync.RWMutex
+	clients  map[*Client]struct{}
+	users    map[domain.EntityID]map[*Client]struct{}
+	channels map[domain.EntityID]map[*Client]struct{}
+	closed   bool
+
+	// writers tracks the clients' write goroutines, which close their
+	// connections on exit.
+	writers sync.WaitGroup
+
+	pingPeriod time.Duration
+	pongWait   time.Duration
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients:    make(map[*Client]struct{}),
+		users:      make(map[domain.EntityID]map[*Client]struct{}),
+		channels:   make(map[domain.EntityID]map[*Client]struct{}),
+		pingPeriod: pingPeriod,
+		pongWait:   pongWait,
+	}
+}
+
+// register adds c to the hub. It reports false once the hub is closed.
+func (h *Hub) register(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.writers.Add(1)
+	h.clients[c] = struct{}{}
+	if h.users[c.userID] == nil {
+		h.users[c.userID] = make(map[*Client]struct{})
+	}
+	h.users[c.userID][c] = struct{}{}
+	return true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+
+	delete(h.users[c.userID], c)
+	if len(h.users[c.userID]) == 0 {
+		delete(h.users, c.userID)
+	}
+
+	for channelID := range c.channels {
+		delete(h.channels[channelID], c)
+		if len(h.channels[channelID]) == 0 {
+			delete(h.channels, channelID)
+		}
+	}
+
+	c.close()
+}
+
+func (h *Hub) subscribe(c *Client, channelID domain.EntityID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	if h.channels[channelID] == nil {
+		h.channels[channelID] = make(map[*Client]struct{})
+	}
+	h.channels[channelID][c] = struct{}{}
+	c.channels[channelID] = struct{}{}
+}
+
+func (h *Hub) unsubscribe(c *Client, channelID domain.EntityID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.channels[channelID], c)
+	if len(h.channels[channelID]) == 0 {
+		delete(h.channels, channelID)
+	}
+	delete(c.channels, channelID)
+}
+
+// unsubscribeUser removes every client of userID from channelID.
+func (h *Hub) unsubscribeUser(channelID, userID domain.EntityID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.users[userID] {
+		delete(h.channels[channelID], c)
+		delete(c.channels, channelID)
+	}
+	if len(h.channels[channelID]) == 0 {
+		delete(h.channels, channelID)
+	}
+}
+
+// unsubscribeAll removes every client from channelID.
+func (h *Hub) unsubscribeAll(channelID domain.EntityID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.channels[channelID] {
+		delete(c.channels, channelID)
+	}
+	delete(h.channels, channelID)
+}
+
+// ConnectionCount returns the number of currently connected clients.
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+func (h *Hub) publishToChannel(channelID domain.EntityID, event Event) {
+	payload, err := encode(event)
+	if err != nil {
+		slog.Error("realtime: failed to encode event", "type", event.Type, "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.channels[channelID]))
+	for c := range h.channels[channelID] {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	h.deliver(targets, payload)
+}
+
+func (h *Hub) publishToUsers(userIDs []domain.EntityID, event Event) {
+	payload, err := encode(event)
+	if err != nil {
+		slog.Error("realtime: failed to encode event", "type", event.Type, "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	seen := make(map[*Client]struct{})
+	targets := make([]*Client, 0)
+	for _, userID := range userIDs {
+		for c := range h.users[userID] {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+			targets = append(targets, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	h.deliver(targets, payload)
+}
+
+func (h *Hub) deliver(targets []*Client, payload []byte) {
+	for _, c := range targets {
+		if !c.enqueue(payload) {
+			slog.Warn("realtime: dropping slow client", "user_id", c.userID.String())
+			h.unregister(c)
+		}
+	}
+}
+
+// Close disconnects every client and turns away new ones.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		h.unregister(c)
+	}
+}
+
+// Check reports an error once the hub is closed and turns away clients.
+func (h *Hub) Check(ctx context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.closed {
+		return errors.New("realtime hub is closed")
+	}
+	return nil
+}
+
+// Shutdown closes the hub and waits until every connection has been sent its
+// close frame and closed, or until ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.Close()
+
+	done := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,272 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+)
+
+func newTestServer(t *testing.T, hub *Hub, allowed domain.EntityID) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := domain.ParseEntityID(r.URL.Query().Get("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.ServeClient(conn, userID, func(_, channelID domain.EntityID) (bool, error) {
+			return channelID == allowed, nil
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server, userID domain.EntityID) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?user_id=" + userID.String()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("error dialing websocket. Err: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readJSON(t *testing.T, conn *websocket.Conn, v interface{}) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(v); err != nil {
+		t.Fatalf("error reading from websocket. Err: %v", err)
+	}
+}
+
+func waitForConnections(t *testing.T, hub *Hub, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.ConnectionCount() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d connections; got %d", want, hub.ConnectionCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChannelMessageDelivery(t *testing.T) {
+	hub := NewHub()
+	channelID := domain.NewEntityID()
+	server := newTestServer(t, hub, channelID)
+	conn := dial(t, server, domain.NewEntityID())
+
+	conn.WriteJSON(command{Type: commandSubscribe, ChannelID: channelID})
+	var ack reply
+	readJSON(t, conn, &ack)
+	if ack.Type != "subscribed" {
+		t.Fatalf("expected subscribed reply; got %+v", ack)
+	}
+
+	NewPublisher(hub, NewStream()).ChannelMessageCreated(&model.ChannelMessage{BaseEntity: domain.BaseEntity{ID: domain.NewEntityID()}, ChannelID: channelID, SenderID: domain.NewEntityID(), Content: "hello"})
+
+	var event struct {
+		Type string               `json:"type"`
+		Data model.ChannelMessage `json:"data"`
+	}
+	readJSON(t, conn, &event)
+	if event.Type != EventChannelMessageCreated {
+		t.Errorf("expected event type %s; got %s", EventChannelMessageCreated, event.Type)
+	}
+	if event.Data.Content != "hello" {
+		t.Errorf("expected content hello; got %s", event.Data.Content)
+	}
+}
+
+func TestRemovedMemberStopsReceivingChannelMessages(t *testing.T) {
+	hub := NewHub()
+	channelID := domain.NewEntityID()
+	server := newTestServer(t, hub, channelID)
+	userID := domain.NewEntityID()
+	conn := dial(t, server, userID)
+
+	conn.WriteJSON(command{Type: commandSubscribe, ChannelID: channelID})
+	var ack reply
+	readJSON(t, conn, &ack)
+	if ack.Type != "subscribed" {
+		t.Fatalf("expected subscribed reply; got %+v", ack)
+	}
+
+	publisher := NewPublisher(hub, NewStream())
+	publisher.ChannelMemberRemoved(channelID, userID)
+	var event Event
+	readJSON(t, conn, &event)
+	if event.Type != EventChannelMemberRemoved {
+		t.Fatalf("expected event type %s; got %s", EventChannelMemberRemoved, event.Type)
+	}
+
+	publisher.ChannelMessageCreated(&model.ChannelMessage{BaseEntity: domain.BaseEntity{ID: domain.NewEntityID()}, ChannelID: channelID, SenderID: domain.NewEntityID(), Content: "after"})
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Errorf("expected removed member to receive nothing")
+	}
+}
+
+func TestSubscribeRejectedForNonMember(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(t, hub, domain.NewEntityID())
+	conn := dial(t, server, domain.NewEntityID())
+
+	conn.WriteJSON(command{Type: commandSubscribe, ChannelID: domain.NewEntityID()})
+	var ack reply
+	readJSON(t, conn, &ack)
+	if ack.Type != "error" {
+		t.Errorf("expected error reply; got %+v", ack)
+	}
+}
+
+func TestPrivateMessageDelivery(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(t, hub, domain.NewEntityID())
+	receiverID := domain.NewEntityID()
+	receiver := dial(t, server, receiverID)
+	bystander := dial(t, server, domain.NewEntityID())
+	waitForConnections(t, hub, 2)
+
+	NewPublisher(hub, NewStream()).PrivateMessageCreated(&model.PrivateMessage{SenderID: domain.NewEntityID(), ReceiverID: receiverID, Content: "psst"})
+
+	var event Event
+	readJSON(t, receiver, &event)
+	if event.Type != EventPrivateMessageCreated {
+		t.Errorf("expected event type %s; got %s", EventPrivateMessageCreated, event.Type)
+	}
+
+	bystander.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := bystander.ReadMessage(); err == nil {
+		t.Errorf("expected bystander to receive nothing")
+	}
+}
+
+func TestSlowConsumerIsDropped(t *testing.T) {
+	hub := NewHub()
+	c := &Client{
+		hub:      hub,
+		userID:   domain.NewEntityID(),
+		send:     make(chan []byte, 1),
+		channels: make(map[domain.EntityID]struct{}),
+	}
+	hub.register(c)
+
+	publisher := NewPublisher(hub, NewStream())
+	message := &model.PrivateMessage{SenderID: domain.NewEntityID(), ReceiverID: c.userID, Content: "one"}
+	publisher.PrivateMessageCreated(message)
+	if hub.ConnectionCount() != 1 {
+		t.Fatalf("expected client to stay connected while buffer has room")
+	}
+
+	publisher.PrivateMessageCreated(message)
+	if hub.ConnectionCount() != 0 {
+		t.Errorf("expected slow client to be dropped; got %d connections", hub.ConnectionCount())
+	}
+}
+
+func TestDeletedChannelIsUnsubscribed(t *testing.T) {
+	hub := NewHub()
+	c := &Client{
+		hub:      hub,
+		userID:   domain.NewEntityID(),
+		send:     make(chan []byte, 1),
+		channels: make(map[domain.EntityID]struct{}),
+	}
+	hub.register(c)
+	channelID := domain.NewEntityID()
+	hub.subscribe(c, channelID)
+
+	NewPublisher(hub, NewStream()).ChannelDeleted(channelID)
+
+	if _, ok := hub.channels[channelID]; ok {
+		t.Error("expected the deleted channel's subscriptions to be dropped")
+	}
+	if _, ok := c.channels[channelID]; ok {
+		t.Error("expected the client to be unsubscribed from the deleted channel")
+	}
+}
+
+func TestShutdownClosesConnections(t *testing.T) {
+	hub := NewHub()
+	server := newTestServer(t, hub, domain.NewEntityID())
+	conn := dial(t, server, domain.NewEntityID())
+	waitForConnections(t, hub, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("expected shutdown to finish; got %v", err)
+	}
+
+	for _, c := range []*websocket.Conn{conn, dial(t, server, domain.NewEntityID())} {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := c.ReadMessage()
+		var netErr net.Error
+		if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+			t.Errorf("expected connection to be closed; got %v", err)
+		}
+	}
+	if hub.ConnectionCount() != 0 {
+		t.Errorf("expected no connections after shutdown; got %d", hub.ConnectionCount())
+	}
+}
+
+func TestPingKeepalive(t *testing.T) {
+	hub := NewHub()
+	hub.pingPeriod = 20 * time.Millisecond
+	server := newTestServer(t, hub, domain.NewEntityID())
+	conn := dial(t, server, domain.NewEntityID())
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pinged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a ping from the server")
+	}
+}
+
+func TestEventEncoding(t *testing.T) {
+	channelID := domain.NewEntityID()
+	payload, err := encode(Event{Type: EventChannelMessageCreated, ChannelID: &channelID, Data: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatalf("error encoding event. Err: %v", err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(payload, &decoded)
+	if decoded["channel_id"] != channelID.String() {
+		t.Errorf("expected channel_id %s; got %v", channelID, decoded["channel_id"])
+	}
+}
@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+)
+
+// Publisher turns domain changes into events and delivers them to websocket
+// clients through the Hub and to event-stream subscribers through the Stream.
+type Publisher struct {
+	hub    *Hub
+	stream *Stream
+}
+
+func NewPublisher(hub *Hub, stream *Stream) *Publisher {
+	return &Publisher{
+		hub:    hub,
+		stream: stream,
+	}
+}
+
+func (p *Publisher) ChannelMessageCreated(message *model.ChannelMessage) {
+	p.publishToChannel(message.ChannelID, EventChannelMessageCreated, message)
+}
+
+func (p *Publisher) ChannelMessageUpdated(message *model.ChannelMessage) {
+	p.publishToChannel(message.ChannelID, EventChannelMessageUpdated, message)
+}
+
+func (p *Publisher) ChannelMessageDeleted(channelID, messageID domain.EntityID) {
+	p.publishToChannel(channelID, EventChannelMessageDeleted, messageDeleted{ID: messageID})
+}
+
+func (p *Publisher) ChannelMemberAdded(channelID, userID domain.EntityID) {
+	p.publishToChannel(channelID, EventChannelMemberAdded, membershipChanged{UserID: userID})
+}
+
+// ChannelMemberRemoved tells the channel, the removed user included, and then
+// stops delivering the channel's events to the user's connections and event
+// streams.
+func (p *Publisher) ChannelMemberRemoved(channelID, userID domain.EntityID) {
+	p.publishToChannel(channelID, EventChannelMemberRemoved, membershipChanged{UserID: userID})
+	p.hub.unsubscribeUser(channelID, userID)
+	p.stream.unsubscribeUser(channelID, userID)
+}
+
+func (p *Publisher) ChannelMemberRoleChanged(channelID, userID domain.EntityID, role model.ChannelRole) {
+	p.publishToChannel(channelID, EventChannelMemberRole, membershipChanged{UserID: userID, Role: string(role)})
+}
+
+// ChannelDeleted drops the channel's event history, ends its event streams
+// and unsubscribes its websocket clients.
+func (p *Publisher) ChannelDeleted(channelID domain.EntityID) {
+	p.stream.forget(channelID)
+	p.hub.unsubscribeAll(channelID)
+}
+
+// PrivateMessageCreated pushes a new private message to every websocket
+// connection of both the sender and the receiver.
+func (p *Publisher) PrivateMessageCreated(message *model.PrivateMessage) {
+	p.hub.publishToUsers([]domain.EntityID{message.SenderID, message.ReceiverID}, Event{
+		Type: EventPrivateMessageCreated,
+		Data: message,
+	})
+}
+
+func (p *Publisher) publishToChannel(channelID domain.EntityID, eventType string, data interface{}) {
+	event := p.stream.publish(channelID, Event{
+		Type:      eventType,
+		ChannelID: &channelID,
+		Data:      data,
+	})
+	p.hub.publishToChannel(channelID, event)
+}
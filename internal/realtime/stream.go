@@ -0,0 +1,260 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+const (
+	// streamHistorySize is how many recent events are kept per channel for
+	// clients resuming with Last-Event-ID.
+	streamHistorySize = 500
+
+	// streamBufferSize is how many events may queue up for a subscriber
+	// before it is closed and expected to reconnect.
+	streamBufferSize = 64
+
+	// streamIdleTTL is how long the history of a channel without
+	// subscribers is kept after its last event. Clients resuming later get
+	// a reset.
+	streamIdleTTL = 15 * time.Minute
+
+	// streamSweepInterval is how often idle channel histories are dropped.
+	streamSweepInterval = time.Minute
+)
+
+// Stream keeps a bounded history of channel events and fans them out to
+// subscribers such as Server-Sent Events connections. Event IDs are seeded
+// from the clock so they keep increasing across restarts.
+//
+// The history of a channel is dropped when the channel is deleted or has
+// been idle for streamIdleTTL, so memory is bounded by the channels in use.
+type Stream struct {
+	mu       sync.Mutex
+	firstID  uint64
+	lastID   uint64
+	channels map[domain.EntityID]*channelStream
+	closed   bool
+
+	// forgottenUpTo is the last event ID of the histories dropped so far.
+	// Any channel may have had events up to it, so a channel starts out
+	// with its history evicted up to there.
+	forgottenUpTo uint64
+	lastSweep     time.Time
+	now           func() time.Time
+}
+
+type channelStream struct {
+	history       []Event
+	evictedUpTo   uint64
+	lastPublished time.Time
+	subscribers   map[*Subscription]struct{}
+}
+
+// Subscription receives a user's live events for a single channel. C is closed when
+// the subscription is cancelled or falls too far behind.
+type Subscription struct {
+	C <-chan Event
+
+	c         chan Event
+	stream    *Stream
+	channelID domain.EntityID
+	userID    domain.EntityID
+	closed    bool
+}
+
+func NewStream() *Stream {
+	seed := uint64(time.Now().UnixMicro())
+	return &Stream{
+		firstID:  seed + 1,
+		lastID:   seed,
+		channels: make(map[domain.EntityID]*channelStream),
+		now:      time.Now,
+	}
+}
+
+func (s *Stream) channel(channelID domain.EntityID) *channelStream {
+	cs, ok := s.channels[channelID]
+	if !ok {
+		cs = &channelStream{evictedUpTo: s.forgottenUpTo, subscribers: make(map[*Subscription]struct{})}
+		s.channels[channelID] = cs
+	}
+	return cs
+}
+
+// publish assigns the next ID to event, records it in the channel history
+// and delivers it to live subscribers.
+func (s *Stream) publish(channelID domain.EntityID, event Event) Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= streamSweepInterval {
+		s.sweepLocked(now)
+	}
+
+	s.lastID++
+	event.ID = s.lastID
+
+	cs := s.channel(channelID)
+	cs.lastPublished = now
+	cs.history = append(cs.history, event)
+	if len(cs.history) > streamHistorySize {
+		cs.evictedUpTo = cs.history[0].ID
+		cs.history = cs.history[1:]
+	}
+
+	for sub := range cs.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			s.closeLocked(sub)
+		}
+	}
+
+	return event
+}
+
+// Subscribe starts a subscription of userID to channelID. When lastEventID is non-zero
+// the events published after it are returned as backlog; complete is false
+// if some of those events are no longer available. Once the stream is closed
+// the subscription starts out closed.
+func (s *Stream) Subscribe(channelID, userID domain.EntityID, lastEventID uint64) (sub *Subscription, backlog []Event, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := make(chan Event, streamBufferSize)
+	sub = &Subscription{C: c, c: c, stream: s, channelID: channelID, userID: userID}
+	if s.closed {
+		sub.closed = true
+		close(c)
+		return sub, nil, true
+	}
+	cs := s.channel(channelID)
+	cs.subscribers[sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil, true
+	}
+
+	complete = lastEventID >= s.firstID-1 && lastEventID <= s.lastID && lastEventID >= cs.evictedUpTo
+	for _, event := range cs.history {
+		if event.ID > lastEventID {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, complete
+}
+
+// forget drops the history of a deleted channel and closes its
+// subscriptions.
+func (s *Stream) forget(channelID domain.EntityID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs, ok := s.channels[channelID]
+	if !ok {
+		return
+	}
+	for sub := range cs.subscribers {
+		s.closeLocked(sub)
+	}
+	s.dropLocked(channelID, cs)
+}
+
+// unsubscribeUser closes the subscriptions of userID to channelID, once the
+// user is no longer a member.
+func (s *Stream) unsubscribeUser(channelID, userID domain.EntityID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs, ok := s.channels[channelID]
+	if !ok {
+		return
+	}
+	for sub := range cs.subscribers {
+		if sub.userID == userID {
+			s.closeLocked(sub)
+		}
+	}
+}
+
+// sweepLocked drops the histories of the channels that have had neither
+// subscribers nor events for streamIdleTTL.
+func (s *Stream) sweepLocked(now time.Time) {
+	for channelID, cs := range s.channels {
+		if len(cs.subscribers) == 0 && now.Sub(cs.lastPublished) >= streamIdleTTL {
+			s.dropLocked(channelID, cs)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (s *Stream) dropLocked(channelID domain.EntityID, cs *channelStream) {
+	if n := len(cs.history); n > 0 {
+		s.forgottenUpTo = max(s.forgottenUpTo, cs.history[n-1].ID)
+	}
+	delete(s.channels, channelID)
+}
+
+// Close cancels the subscription.
+func (sub *Subscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	sub.stream.closeLocked(sub)
+}
+
+func (s *Stream) closeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.c)
+
+	cs := s.channels[sub.channelID]
+	delete(cs.subscribers, sub)
+	if len(cs.subscribers) == 0 && len(cs.history) == 0 {
+		delete(s.channels, sub.channelID)
+	}
+}
+
+// Close cancels every subscription and turns away new ones.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, cs := range s.channels {
+		for sub := range cs.subscribers {
+			s.closeLocked(sub)
+		}
+	}
+}
+
+// SubscriberCount returns the number of live subscriptions across channels.
+func (s *Stream) SubscriberCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, cs := range s.channels {
+		n += len(cs.subscribers)
+	}
+	return n
+}
+
+// Check reports an error once the stream is closed and no longer delivers
+// events.
+func (s *Stream) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("event stream is closed")
+	}
+	return nil
+}
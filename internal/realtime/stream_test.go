@@ -0,0 +1,167 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+)
+
+func TestStreamDeliversLiveEvents(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	sub, backlog, complete := stream.Subscribe(channelID, domain.NewEntityID(), 0)
+	defer sub.Close()
+
+	if len(backlog) != 0 || !complete {
+		t.Fatalf("expected empty complete backlog; got %d events, complete=%v", len(backlog), complete)
+	}
+
+	published := stream.publish(channelID, Event{Type: EventChannelMemberAdded})
+	stream.publish(domain.NewEntityID(), Event{Type: EventChannelMemberAdded})
+
+	event := <-sub.C
+	if event.ID != published.ID {
+		t.Errorf("expected event %d; got %d", published.ID, event.ID)
+	}
+	select {
+	case event := <-sub.C:
+		t.Errorf("expected no event from another channel; got %+v", event)
+	default:
+	}
+}
+
+func TestStreamResumesFromLastEventID(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	first := stream.publish(channelID, Event{Type: EventChannelMessageCreated})
+	second := stream.publish(channelID, Event{Type: EventChannelMessageUpdated})
+	third := stream.publish(channelID, Event{Type: EventChannelMessageDeleted})
+
+	sub, backlog, complete := stream.Subscribe(channelID, domain.NewEntityID(), first.ID)
+	defer sub.Close()
+
+	if !complete {
+		t.Errorf("expected complete backlog")
+	}
+	if len(backlog) != 2 || backlog[0].ID != second.ID || backlog[1].ID != third.ID {
+		t.Errorf("expected events %d and %d; got %+v", second.ID, third.ID, backlog)
+	}
+}
+
+func TestStreamReportsGapAfterEviction(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	first := stream.publish(channelID, Event{Type: EventChannelMessageCreated})
+	for i := 0; i < streamHistorySize+1; i++ {
+		stream.publish(channelID, Event{Type: EventChannelMessageCreated})
+	}
+
+	sub, backlog, complete := stream.Subscribe(channelID, domain.NewEntityID(), first.ID)
+	defer sub.Close()
+
+	if complete {
+		t.Errorf("expected incomplete backlog after eviction")
+	}
+	if len(backlog) != streamHistorySize {
+		t.Errorf("expected %d events; got %d", streamHistorySize, len(backlog))
+	}
+}
+
+func TestStreamReportsGapForUnknownID(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	sub, _, complete := stream.Subscribe(channelID, domain.NewEntityID(), 42)
+	defer sub.Close()
+
+	if complete {
+		t.Errorf("expected incomplete backlog for an ID from a previous process")
+	}
+}
+
+func TestStreamClosesSlowSubscriber(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	sub, _, _ := stream.Subscribe(channelID, domain.NewEntityID(), 0)
+
+	for i := 0; i < streamBufferSize+1; i++ {
+		stream.publish(channelID, Event{Type: EventChannelMessageCreated})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != streamBufferSize {
+		t.Errorf("expected %d buffered events before close; got %d", streamBufferSize, received)
+	}
+}
+
+func TestStreamForgetsDeletedChannel(t *testing.T) {
+	stream := NewStream()
+	channelID := domain.NewEntityID()
+	last := stream.publish(channelID, Event{Type: EventChannelMessageCreated})
+	sub, _, _ := stream.Subscribe(channelID, domain.NewEntityID(), 0)
+
+	stream.forget(channelID)
+
+	if _, ok := <-sub.C; ok {
+		t.Error("expected the subscription to be closed")
+	}
+	if n := len(stream.channels); n != 0 {
+		t.Errorf("expected no channel state left; got %d channels", n)
+	}
+	resumed, backlog, complete := stream.Subscribe(channelID, domain.NewEntityID(), last.ID-1)
+	defer resumed.Close()
+	if complete || len(backlog) != 0 {
+		t.Errorf("expected an incomplete empty backlog once the history is gone; got %d events, complete=%v", len(backlog), complete)
+	}
+}
+
+func TestStreamUnsubscribesRemovedMember(t *testing.T) {
+	stream := NewStream()
+	channelID, removedID := domain.NewEntityID(), domain.NewEntityID()
+	removed, _, _ := stream.Subscribe(channelID, removedID, 0)
+	other, _, _ := stream.Subscribe(channelID, domain.NewEntityID(), 0)
+	defer other.Close()
+
+	NewPublisher(NewHub(), stream).ChannelMemberRemoved(channelID, removedID)
+
+	if event, ok := <-removed.C; !ok || event.Type != EventChannelMemberRemoved {
+		t.Fatalf("expected the removal event before the subscription closes; got %+v", event)
+	}
+	if _, ok := <-removed.C; ok {
+		t.Error("expected the removed member's subscription to be closed")
+	}
+	if n := stream.SubscriberCount(); n != 1 {
+		t.Errorf("expected the other subscription to remain; got %d subscriptions", n)
+	}
+}
+
+func TestStreamDropsIdleChannels(t *testing.T) {
+	stream := NewStream()
+	now := time.Now()
+	stream.now = func() time.Time { return now }
+
+	idle, watched := domain.NewEntityID(), domain.NewEntityID()
+	seen := stream.publish(idle, Event{Type: EventChannelMessageCreated})
+	stream.publish(watched, Event{Type: EventChannelMessageCreated})
+	sub, _, _ := stream.Subscribe(watched, domain.NewEntityID(), 0)
+	defer sub.Close()
+
+	now = now.Add(streamIdleTTL)
+	stream.publish(domain.NewEntityID(), Event{Type: EventChannelMessageCreated})
+
+	if _, ok := stream.channels[idle]; ok {
+		t.Error("expected the idle channel to be dropped")
+	}
+	if _, ok := stream.channels[watched]; !ok {
+		t.Error("expected the channel with a subscriber to be kept")
+	}
+
+	resumed, _, complete := stream.Subscribe(idle, domain.NewEntityID(), seen.ID)
+	defer resumed.Close()
+	if !complete {
+		t.Error("expected a client that saw the last event to resume completely")
+	}
+}
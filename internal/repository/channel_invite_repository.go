@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type ChannelInviteRepository interface {
+	Create(ctx context.Context, invite *model.ChannelInvite) error
+	GetByID(ctx context.Context, channelID, id domain.EntityID) (*model.ChannelInvite, error)
+	GetByCode(ctx context.Context, code string) (*model.ChannelInvite, error)
+	// UpdateStatus moves a pending invite to status. It fails with
+	// ErrConflict when the invite is no longer pending.
+	UpdateStatus(ctx context.Context, id domain.EntityID, status model.InviteStatus) error
+	ListByChannel(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error)
+	ListPendingForUser(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error)
+	ConsumeUse(ctx context.Context, id domain.EntityID) (bool, error)
+}
+
+type channelInviteRepository struct {
+	db *gorm.DB
+}
+
+func NewChannelInviteRepository(db *gorm.DB) ChannelInviteRepository {
+	return &channelInviteRepository{db: db}
+}
+
+func (r *channelInviteRepository) Create(ctx context.Context, invite *model.ChannelInvite) error {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.Create")
+	defer span.End()
+
+	err := db.Create(invite).Error
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return errors.NewAppError(errors.ErrNotFound, "Channel or user not found")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to create invite", err)
+	}
+	return nil
+}
+
+func (r *channelInviteRepository) GetByID(ctx context.Context, channelID, id domain.EntityID) (*model.ChannelInvite, error) {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.GetByID")
+	defer span.End()
+
+	var invite model.ChannelInvite
+	err := db.First(&invite, "id = ? AND channel_id = ?", id.String(), channelID.String()).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Invite not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get invite", err)
+	}
+	return &invite, nil
+}
+
+func (r *channelInviteRepository) GetByCode(ctx context.Context, code string) (*model.ChannelInvite, error) {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.GetByCode")
+	defer span.End()
+
+	var invite model.ChannelInvite
+	err := db.Where("code = ?", code).First(&invite).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Invite not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get invite", err)
+	}
+	return &invite, nil
+}
+
+func (r *channelInviteRepository) UpdateStatus(ctx context.Context, id domain.EntityID, status model.InviteStatus) error {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.UpdateStatus")
+	defer span.End()
+
+	result := db.Model(&model.ChannelInvite{}).
+		Where("id = ? AND status = ?", id.String(), model.InviteStatusPending).
+		Updates(map[string]any{"status": status, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return errors.Wrap(errors.ErrInternal, "Failed to update invite", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewAppError(errors.ErrConflict, "Invite is no longer pending")
+	}
+	return nil
+}
+
+func (r *channelInviteRepository) ListByChannel(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error) {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.ListByChannel")
+	defer span.End()
+
+	var invites []*model.ChannelInvite
+	query := db.Where("channel_id = ?", channelID.String())
+	err := paginate(query, page, "created_at", "id", true).Find(&invites).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list invites", err)
+	}
+	return pagination.NewResult(invites, page, inviteCursor), nil
+}
+
+func (r *channelInviteRepository) ListPendingForUser(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error) {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.ListPendingForUser")
+	defer span.End()
+
+	var invites []*model.ChannelInvite
+	query := db.Where("invitee_id = ? AND status = ?", userID.String(), model.InviteStatusPending)
+	err := paginate(query, page, "created_at", "id", true).Find(&invites).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list invites", err)
+	}
+	return pagination.NewResult(invites, page, inviteCursor), nil
+}
+
+func inviteCursor(i *model.ChannelInvite) pagination.Cursor {
+	return entityCursor(&i.BaseEntity)
+}
+
+// ConsumeUse atomically records one use of the invite. It reports false when
+// the invite is no longer pending, has expired or has no uses left.
+func (r *channelInviteRepository) ConsumeUse(ctx context.Context, id domain.EntityID) (bool, error) {
+	db, span := startSpan(ctx, r.db, "ChannelInviteRepository.ConsumeUse")
+	defer span.End()
+
+	result := db.Model(&model.ChannelInvite{}).
+		Where("id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)",
+			id.String(), model.InviteStatusPending, time.Now()).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, errors.Wrap(errors.ErrInternal, "Failed to use invite", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
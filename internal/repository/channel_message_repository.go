@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type ChannelMessageRepository interface {
+	Create(ctx context.Context, message *model.ChannelMessage) error
+	GetByID(ctx context.Context, channelID, id domain.EntityID) (*model.ChannelMessage, error)
+	Update(ctx context.Context, message *model.ChannelMessage) error
+	// Delete soft-deletes the message, provided it is still at the given
+	// version.
+	Delete(ctx context.Context, channelID, id domain.EntityID, version int64) error
+	ListByChannel(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelMessage], error)
+}
+
+type channelMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewChannelMessageRepository(db *gorm.DB) ChannelMessageRepository {
+	return &channelMessageRepository{db: db}
+}
+
+func (r *channelMessageRepository) Create(ctx context.Context, message *model.ChannelMessage) error {
+	db, span := startSpan(ctx, r.db, "ChannelMessageRepository.Create")
+	defer span.End()
+
+	err := db.Create(message).Error
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return errors.NewAppError(errors.ErrNotFound, "Channel or sender not found")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to create message", err)
+	}
+	return nil
+}
+
+func (r *channelMessageRepository) GetByID(ctx context.Context, channelID, id domain.EntityID) (*model.ChannelMessage, error) {
+	db, span := startSpan(ctx, r.db, "ChannelMessageRepository.GetByID")
+	defer span.End()
+
+	var message model.ChannelMessage
+	err := db.First(&message, "id = ? AND channel_id = ?", id.String(), channelID.String()).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Message not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get message", err)
+	}
+	return &message, nil
+}
+
+// Update saves the message if it has not been changed since it was loaded,
+// returning ErrPreconditionFailed otherwise.
+func (r *channelMessageRepository) Update(ctx context.Context, message *model.ChannelMessage) error {
+	db, span := startSpan(ctx, r.db, "ChannelMessageRepository.Update")
+	defer span.End()
+
+	updated, err := updateVersioned(db, message, &message.BaseEntity)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "Failed to update message", err)
+	}
+	if !updated {
+		return errors.NewAppError(errors.ErrPreconditionFailed, "Message was modified concurrently")
+	}
+	return nil
+}
+
+func (r *channelMessageRepository) Delete(ctx context.Context, channelID, id domain.EntityID, version int64) error {
+	db, span := startSpan(ctx, r.db, "ChannelMessageRepository.Delete")
+	defer span.End()
+
+	result := db.Where("version = ?", version).
+		Delete(&model.ChannelMessage{}, "id = ? AND channel_id = ?", id.String(), channelID.String())
+	if result.Error != nil {
+		return errors.Wrap(errors.ErrInternal, "Failed to delete message", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewAppError(errors.ErrPreconditionFailed, "Message was modified concurrently")
+	}
+	return nil
+}
+
+// ListByChannel lists the channel's messages, newest first.
+func (r *channelMessageRepository) ListByChannel(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelMessage], error) {
+	db, span := startSpan(ctx, r.db, "ChannelMessageRepository.ListByChannel")
+	defer span.End()
+
+	var messages []*model.ChannelMessage
+	query := db.Where("channel_id = ?", channelID.String())
+	err := paginate(query, page, "created_at", "id", true).Find(&messages).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list messages", err)
+	}
+	return pagination.NewResult(messages, page, channelMessageCursor), nil
+}
+
+func channelMessageCursor(m *model.ChannelMessage) pagination.Cursor {
+	return entityCursor(&m.BaseEntity)
+}
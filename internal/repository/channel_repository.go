@@ -1,22 +1,35 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/ruslanguns/go-chat/internal/domain"
 	"github.com/ruslanguns/go-chat/internal/domain/model"
 	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"gorm.io/gorm"
 )
 
 type ChannelRepository interface {
-	Create(channel *model.Channel) error
-	GetByID(id domain.EntityID) (*model.Channel, error)
-	GetByName(name string) (*model.Channel, error)
-	Update(channel *model.Channel) error
-	Delete(id domain.EntityID) error
-	List(offset, limit int) ([]*model.Channel, error)
-	AddUser(channelID, userID domain.EntityID) error
-	RemoveUser(channelID, userID domain.EntityID) error
-	GetUsers(channelID domain.EntityID, offset, limit int) ([]*model.User, error)
+	Create(ctx context.Context, channel *model.Channel) error
+	GetByID(ctx context.Context, id domain.EntityID) (*model.Channel, error)
+	GetByName(ctx context.Context, name string) (*model.Channel, error)
+	Update(ctx context.Context, channel *model.Channel) error
+	// Delete soft-deletes the channel, provided it is still at the given
+	// version.
+	Delete(ctx context.Context, id domain.EntityID, version int64) error
+	List(ctx context.Context, page pagination.Page) (*pagination.Result[*model.Channel], error)
+	ListVisibleTo(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Channel], error)
+	ListWithDeleted(ctx context.Context, scope DeletedScope, page pagination.Page) (*pagination.Result[*model.Channel], error)
+	Restore(ctx context.Context, id domain.EntityID) error
+	Purge(ctx context.Context, id domain.EntityID) error
+	AddUser(ctx context.Context, channelID, userID domain.EntityID, role model.ChannelRole) error
+	RemoveUser(ctx context.Context, channelID, userID domain.EntityID) error
+	GetUsers(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelUser], error)
+	IsMember(ctx context.Context, channelID, userID domain.EntityID) (bool, error)
+	GetMember(ctx context.Context, channelID, userID domain.EntityID) (*model.ChannelMember, error)
+	UpdateMemberRole(ctx context.Context, channelID, userID domain.EntityID, role model.ChannelRole) error
 }
 
 type channelRepository struct {
@@ -27,81 +40,147 @@ func NewChannelRepository(db *gorm.DB) ChannelRepository {
 	return &channelRepository{db: db}
 }
 
-func (r *channelRepository) Create(channel *model.Channel) error {
-	err := r.db.Create(channel).Error
+func (r *channelRepository) Create(ctx context.Context, channel *model.Channel) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.Create")
+	defer span.End()
+
+	err := db.Create(channel).Error
 	if err != nil {
-		if r.db.Error != nil && r.db.Error.Error() == "UNIQUE constraint failed: channels.name" {
+		if isUniqueViolation(err, "name") {
 			return errors.NewAppError(errors.ErrAlreadyExists, "A channel with this name already exists")
 		}
-		return errors.NewAppError(errors.ErrInternal, "Failed to create channel")
+		return errors.Wrap(errors.ErrInternal, "Failed to create channel", err)
 	}
 	return nil
 }
 
-func (r *channelRepository) GetByID(id domain.EntityID) (*model.Channel, error) {
+func (r *channelRepository) GetByID(ctx context.Context, id domain.EntityID) (*model.Channel, error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.GetByID")
+	defer span.End()
+
 	var channel model.Channel
-	err := r.db.First(&channel, "id = ?", id.String()).Error
+	err := db.First(&channel, "id = ?", id.String()).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NewAppError(errors.ErrNotFound, "Channel not found")
 		}
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get channel")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get channel", err)
 	}
 	return &channel, nil
 }
 
-func (r *channelRepository) GetByName(name string) (*model.Channel, error) {
+func (r *channelRepository) GetByName(ctx context.Context, name string) (*model.Channel, error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.GetByName")
+	defer span.End()
+
 	var channel model.Channel
-	err := r.db.Where("name = ?", name).First(&channel).Error
+	err := db.Where("name = ?", name).First(&channel).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NewAppError(errors.ErrNotFound, "Channel not found")
 		}
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get channel")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get channel", err)
 	}
 	return &channel, nil
 }
 
-func (r *channelRepository) Update(channel *model.Channel) error {
-	err := r.db.Save(channel).Error
+// Update saves the channel if it has not been changed since it was loaded,
+// returning ErrPreconditionFailed otherwise.
+func (r *channelRepository) Update(ctx context.Context, channel *model.Channel) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.Update")
+	defer span.End()
+
+	updated, err := updateVersioned(db, channel, &channel.BaseEntity)
 	if err != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to update channel")
+		if isUniqueViolation(err, "name") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "A channel with this name already exists")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to update channel", err)
+	}
+	if !updated {
+		return errors.NewAppError(errors.ErrPreconditionFailed, "Channel was modified concurrently")
 	}
 	return nil
 }
 
-func (r *channelRepository) Delete(id domain.EntityID) error {
-	result := r.db.Delete(&model.Channel{}, "id = ?", id.String())
+func (r *channelRepository) Delete(ctx context.Context, id domain.EntityID, version int64) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.Delete")
+	defer span.End()
+
+	result := db.Where("version = ?", version).Delete(&model.Channel{}, "id = ?", id.String())
 	if result.Error != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to delete channel")
+		return errors.Wrap(errors.ErrInternal, "Failed to delete channel", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.NewAppError(errors.ErrNotFound, "Channel not found")
+		return errors.NewAppError(errors.ErrPreconditionFailed, "Channel was modified concurrently")
 	}
 	return nil
 }
 
-func (r *channelRepository) List(offset, limit int) ([]*model.Channel, error) {
+func (r *channelRepository) List(ctx context.Context, page pagination.Page) (*pagination.Result[*model.Channel], error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.List")
+	defer span.End()
+
 	var channels []*model.Channel
-	err := r.db.Offset(offset).Limit(limit).Find(&channels).Error
+	err := paginate(db, page, "created_at", "id", false).Find(&channels).Error
 	if err != nil {
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to list channels")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list channels", err)
 	}
-	return channels, nil
+	return pagination.NewResult(channels, page, channelCursor), nil
 }
 
-func (r *channelRepository) AddUser(channelID, userID domain.EntityID) error {
-	err := r.db.Exec("INSERT INTO user_channels (channel_id, user_id) VALUES (?, ?)", channelID.String(), userID.String()).Error
+// ListVisibleTo lists every channel except private channels the user is not a
+// member of.
+func (r *channelRepository) ListVisibleTo(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Channel], error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.ListVisibleTo")
+	defer span.End()
+
+	var channels []*model.Channel
+	query := db.Where("visibility <> ? OR id IN (?)",
+		model.VisibilityPrivate,
+		db.Model(&model.ChannelMember{}).Select("channel_id").Where("user_id = ?", userID.String()),
+	)
+	err := paginate(query, page, "created_at", "id", false).Find(&channels).Error
 	if err != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to add user to channel")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list channels", err)
+	}
+	return pagination.NewResult(channels, page, channelCursor), nil
+}
+
+func channelCursor(c *model.Channel) pagination.Cursor {
+	return entityCursor(&c.BaseEntity)
+}
+
+func (r *channelRepository) AddUser(ctx context.Context, channelID, userID domain.EntityID, role model.ChannelRole) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.AddUser")
+	defer span.End()
+
+	member := &model.ChannelMember{
+		ChannelID: channelID,
+		UserID:    userID,
+		Role:      role,
+		JoinedAt:  time.Now(),
+	}
+	err := db.Create(member).Error
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "User is already a member of the channel")
+		}
+		if isForeignKeyViolation(err) {
+			return errors.NewAppError(errors.ErrNotFound, "Channel or user not found")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to add user to channel", err)
 	}
 	return nil
 }
 
-func (r *channelRepository) RemoveUser(channelID, userID domain.EntityID) error {
-	result := r.db.Exec("DELETE FROM user_channels WHERE channel_id = ? AND user_id = ?", channelID.String(), userID.String())
+func (r *channelRepository) RemoveUser(ctx context.Context, channelID, userID domain.EntityID) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.RemoveUser")
+	defer span.End()
+
+	result := db.Exec("DELETE FROM user_channels WHERE channel_id = ? AND user_id = ?", channelID.String(), userID.String())
 	if result.Error != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to remove user from channel")
+		return errors.Wrap(errors.ErrInternal, "Failed to remove user from channel", result.Error)
 	}
 	if result.RowsAffected == 0 {
 		return errors.NewAppError(errors.ErrNotFound, "User not found in channel")
@@ -109,15 +188,133 @@ func (r *channelRepository) RemoveUser(channelID, userID domain.EntityID) error
 	return nil
 }
 
-func (r *channelRepository) GetUsers(channelID domain.EntityID, offset, limit int) ([]*model.User, error) {
-	var users []*model.User
-	err := r.db.Table("users").
+// GetUsers lists the members of the channel in the order they joined.
+func (r *channelRepository) GetUsers(ctx context.Context, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelUser], error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.GetUsers")
+	defer span.End()
+
+	var users []*model.ChannelUser
+	query := db.Model(&model.User{}).
+		Select("users.*, user_channels.role, user_channels.joined_at").
 		Joins("JOIN user_channels ON users.id = user_channels.user_id").
-		Where("user_channels.channel_id = ?", channelID.String()).
-		Offset(offset).Limit(limit).
-		Find(&users).Error
+		Where("user_channels.channel_id = ?", channelID.String())
+	err := paginate(query, page, "user_channels.joined_at", "users.id", false).Scan(&users).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get users in channel", err)
+	}
+	return pagination.NewResult(users, page, channelUserCursor), nil
+}
+
+func channelUserCursor(u *model.ChannelUser) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: u.JoinedAt, ID: u.ID.String()}
+}
+
+func (r *channelRepository) IsMember(ctx context.Context, channelID, userID domain.EntityID) (bool, error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.IsMember")
+	defer span.End()
+
+	var count int64
+	err := db.Model(&model.ChannelMember{}).
+		Where("channel_id = ? AND user_id = ?", channelID.String(), userID.String()).
+		Count(&count).Error
 	if err != nil {
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get users in channel")
+		return false, errors.Wrap(errors.ErrInternal, "Failed to check channel membership", err)
 	}
-	return users, nil
+	return count > 0, nil
+}
+
+func (r *channelRepository) GetMember(ctx context.Context, channelID, userID domain.EntityID) (*model.ChannelMember, error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.GetMember")
+	defer span.End()
+
+	var member model.ChannelMember
+	err := db.First(&member, "channel_id = ? AND user_id = ?", channelID.String(), userID.String()).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "User not found in channel")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get channel member", err)
+	}
+	return &member, nil
+}
+
+func (r *channelRepository) UpdateMemberRole(ctx context.Context, channelID, userID domain.EntityID, role model.ChannelRole) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.UpdateMemberRole")
+	defer span.End()
+
+	result := db.Model(&model.ChannelMember{}).
+		Where("channel_id = ? AND user_id = ?", channelID.String(), userID.String()).
+		Update("role", role)
+	if result.Error != nil {
+		return errors.Wrap(errors.ErrInternal, "Failed to update member role", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewAppError(errors.ErrNotFound, "User not found in channel")
+	}
+	return nil
+}
+
+func (r *channelRepository) ListWithDeleted(ctx context.Context, scope DeletedScope, page pagination.Page) (*pagination.Result[*model.Channel], error) {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.ListWithDeleted")
+	defer span.End()
+
+	var channels []*model.Channel
+	err := paginate(withDeleted(db, scope), page, "created_at", "id", false).Find(&channels).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list channels", err)
+	}
+	return pagination.NewResult(channels, page, channelCursor), nil
+}
+
+// Restore undeletes a soft-deleted channel. It fails with ErrAlreadyExists
+// when a live channel has taken the name in the meantime.
+func (r *channelRepository) Restore(ctx context.Context, id domain.EntityID) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.Restore")
+	defer span.End()
+
+	restored, err := restoreDeleted(db, &model.Channel{}, id.String())
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "A channel with this name already exists")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to restore channel", err)
+	}
+	if !restored {
+		return errors.NewAppError(errors.ErrNotFound, "Deleted channel not found")
+	}
+	return nil
+}
+
+// Purge permanently removes a soft-deleted channel along with its
+// memberships, messages and invites.
+func (r *channelRepository) Purge(ctx context.Context, id domain.EntityID) error {
+	db, span := startSpan(ctx, r.db, "ChannelRepository.Purge")
+	defer span.End()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var channel model.Channel
+		err := tx.Unscoped().First(&channel, "id = ? AND deleted_at IS NOT NULL", id.String()).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.NewAppError(errors.ErrNotFound, "Deleted channel not found")
+			}
+			return err
+		}
+
+		for _, m := range []any{&model.ChannelMember{}, &model.ChannelMessage{}, &model.ChannelInvite{}} {
+			if err := tx.Unscoped().Where("channel_id = ?", id.String()).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&channel).Error
+	})
+	if err != nil {
+		var appErr errors.AppError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to purge channel", err)
+	}
+	return nil
 }
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+// PostgreSQL error codes, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// pgKeyDetail matches the detail of a PostgreSQL unique violation, such as
+// "Key (username)=(alice) already exists.".
+var pgKeyDetail = regexp.MustCompile(`^Key \(([^)]*)\)=`)
+
+// uniqueViolation reports whether err is a unique constraint violation and,
+// if so, the columns of the violated constraint as far as the driver names
+// them.
+func uniqueViolation(err error) ([]string, bool) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		if sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique && sqliteErr.ExtendedCode != sqlite3.ErrConstraintPrimaryKey {
+			return nil, false
+		}
+		// SQLite reports "UNIQUE constraint failed: users.username, ...".
+		_, list, _ := strings.Cut(sqliteErr.Error(), ": ")
+		var columns []string
+		for _, column := range strings.Split(list, ", ") {
+			_, name, _ := strings.Cut(column, ".")
+			columns = append(columns, name)
+		}
+		return columns, true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code != pgUniqueViolation {
+			return nil, false
+		}
+		var columns []string
+		if m := pgKeyDetail.FindStringSubmatch(pgErr.Detail); m != nil {
+			columns = strings.Split(m[1], ", ")
+		}
+		return columns, true
+	}
+
+	return nil, false
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation on
+// column, or on any column when column is empty.
+func isUniqueViolation(err error, column string) bool {
+	columns, ok := uniqueViolation(err)
+	return ok && (column == "" || slices.Contains(columns, column))
+}
+
+// isForeignKeyViolation reports whether err is a foreign key constraint
+// violation.
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgForeignKeyViolation
+	}
+	return false
+}
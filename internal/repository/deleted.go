@@ -0,0 +1,36 @@
+package repository
+
+import "gorm.io/gorm"
+
+// DeletedScope selects how listings treat soft-deleted rows.
+type DeletedScope string
+
+const (
+	// OnlyDeleted lists soft-deleted rows only.
+	OnlyDeleted DeletedScope = "only"
+	// IncludeDeleted lists live and soft-deleted rows alike.
+	IncludeDeleted DeletedScope = "include"
+)
+
+func (s DeletedScope) IsValid() bool {
+	return s == OnlyDeleted || s == IncludeDeleted
+}
+
+// withDeleted lifts the soft-delete filter from query according to scope.
+func withDeleted(query *gorm.DB, scope DeletedScope) *gorm.DB {
+	query = query.Unscoped()
+	if scope == OnlyDeleted {
+		query = query.Where("deleted_at IS NOT NULL")
+	}
+	return query
+}
+
+// restoreDeleted clears the deletion mark of the soft-deleted row of model
+// with the given id, bumping its version. It reports false when there is no
+// such row.
+func restoreDeleted(db *gorm.DB, model any, id string) (bool, error) {
+	result := db.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+// paginate scopes query to page, ordering rows by timeColumn and then
+// idColumn, newest first when desc is set. It fetches one row more than the
+// page limit so that pagination.NewResult can tell whether more follow.
+func paginate(query *gorm.DB, page pagination.Page, timeColumn, idColumn string, desc bool) *gorm.DB {
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+
+	if page.After != nil {
+		// Timestamps are written in the server's local time zone, so compare
+		// against the cursor in the same zone.
+		after := page.After.CreatedAt.Local()
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", timeColumn, idColumn, op),
+			after, after, page.After.ID,
+		)
+	} else {
+		query = query.Offset(page.Offset)
+	}
+
+	return query.
+		Order(timeColumn + " " + dir).
+		Order(idColumn + " " + dir).
+		Limit(page.Limit + 1)
+}
+
+// entityCursor returns the pagination cursor of an entity listed in creation
+// order.
+func entityCursor(e *domain.BaseEntity) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.CreatedAt, ID: e.ID.String()}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type PrivateMessageRepository interface {
+	Create(ctx context.Context, message *model.PrivateMessage) error
+	GetByID(ctx context.Context, id domain.EntityID) (*model.PrivateMessage, error)
+	ListConversation(ctx context.Context, userID, counterpartID domain.EntityID, page pagination.Page) (*pagination.Result[*model.PrivateMessage], error)
+	ListConversations(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Conversation], error)
+	MarkConversationRead(ctx context.Context, userID, counterpartID domain.EntityID, readAt time.Time) (int64, error)
+}
+
+type privateMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivateMessageRepository(db *gorm.DB) PrivateMessageRepository {
+	return &privateMessageRepository{db: db}
+}
+
+func (r *privateMessageRepository) Create(ctx context.Context, message *model.PrivateMessage) error {
+	db, span := startSpan(ctx, r.db, "PrivateMessageRepository.Create")
+	defer span.End()
+
+	err := db.Create(message).Error
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return errors.NewAppError(errors.ErrNotFound, "Sender or receiver not found")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to create private message", err)
+	}
+	return nil
+}
+
+func (r *privateMessageRepository) GetByID(ctx context.Context, id domain.EntityID) (*model.PrivateMessage, error) {
+	db, span := startSpan(ctx, r.db, "PrivateMessageRepository.GetByID")
+	defer span.End()
+
+	var message model.PrivateMessage
+	err := db.First(&message, "id = ?", id.String()).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Private message not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get private message", err)
+	}
+	return &message, nil
+}
+
+func (r *privateMessageRepository) ListConversation(ctx context.Context, userID, counterpartID domain.EntityID, page pagination.Page) (*pagination.Result[*model.PrivateMessage], error) {
+	db, span := startSpan(ctx, r.db, "PrivateMessageRepository.ListConversation")
+	defer span.End()
+
+	var messages []*model.PrivateMessage
+	err := paginate(conversationScope(db, userID, counterpartID), page, "created_at", "id", true).
+		Find(&messages).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list private messages", err)
+	}
+	return pagination.NewResult(messages, page, privateMessageCursor), nil
+}
+
+func privateMessageCursor(m *model.PrivateMessage) pagination.Cursor {
+	return entityCursor(&m.BaseEntity)
+}
+
+// ListConversations lists the user's conversations, most recently active
+// first. Conversations are aggregates without a stable key, so they are paged
+// by offset only.
+func (r *privateMessageRepository) ListConversations(ctx context.Context, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Conversation], error) {
+	db, span := startSpan(ctx, r.db, "PrivateMessageRepository.ListConversations")
+	defer span.End()
+
+	// Each conversation's last message is picked alongside its unread count
+	// by window functions, in a single query.
+	type conversationRow struct {
+		model.PrivateMessage
+		CounterpartID domain.EntityID
+		UnreadCount   int64
+	}
+
+	var rows []*conversationRow
+	err := db.Raw(`
+		SELECT * FROM (
+			SELECT pm.*,
+				ROW_NUMBER() OVER (PARTITION BY counterpart_id ORDER BY created_at DESC, id DESC) AS position,
+				SUM(CASE WHEN receiver_id = @user AND read_at IS NULL THEN 1 ELSE 0 END)
+					OVER (PARTITION BY counterpart_id) AS unread_count
+			FROM (
+				SELECT *, CASE WHEN sender_id = @user THEN receiver_id ELSE sender_id END AS counterpart_id
+				FROM private_messages
+				WHERE (sender_id = @user OR receiver_id = @user) AND deleted_at IS NULL
+			) AS pm
+		) AS ranked
+		WHERE position = 1
+		ORDER BY created_at DESC, id DESC
+		LIMIT @limit OFFSET @offset`,
+		map[string]interface{}{"user": userID.String(), "limit": page.Limit + 1, "offset": page.Offset},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list conversations", err)
+	}
+
+	conversations := make([]*model.Conversation, 0, len(rows))
+	for _, row := range rows {
+		conversations = append(conversations, &model.Conversation{
+			CounterpartID: row.CounterpartID,
+			LastMessage:   &row.PrivateMessage,
+			UnreadCount:   row.UnreadCount,
+		})
+	}
+	return pagination.NewResult(conversations, page, nil), nil
+}
+
+func (r *privateMessageRepository) MarkConversationRead(ctx context.Context, userID, counterpartID domain.EntityID, readAt time.Time) (int64, error) {
+	db, span := startSpan(ctx, r.db, "PrivateMessageRepository.MarkConversationRead")
+	defer span.End()
+
+	result := db.Model(&model.PrivateMessage{}).
+		Where("receiver_id = ? AND sender_id = ? AND read_at IS NULL", userID.String(), counterpartID.String()).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return 0, errors.Wrap(errors.ErrInternal, "Failed to mark messages as read", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func conversationScope(db *gorm.DB, userID, counterpartID domain.EntityID) *gorm.DB {
+	return db.Where(
+		"(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+		userID.String(), counterpartID.String(), counterpartID.String(), userID.String(),
+	)
+}
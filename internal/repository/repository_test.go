@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+// openTestDB returns a migrated, empty database. Tests run against SQLite in
+// a temporary directory unless TEST_DB_DRIVER and TEST_DB_URL point them at
+// another database.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		driver, dsn = database.DriverSQLite, filepath.Join(t.TempDir(), "test.db")
+	}
+
+	db, err := database.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("opening %s database: %v", driver, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	gormDB := db.GetDB()
+	for _, table := range []string{"sessions", "channel_messages", "channel_invites", "user_channels", "channels", "private_messages", "users"} {
+		if err := gormDB.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatalf("emptying %s: %v", table, err)
+		}
+	}
+	return gormDB
+}
+
+func newTestUser(t *testing.T, repo UserRepository, username string) *model.User {
+	t.Helper()
+	user, err := model.NewUser(username, username+"@example.com", "supersecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating %s: %v", username, err)
+	}
+	return user
+}
+
+func assertAppError(t *testing.T, err error, kind error, message string) {
+	t.Helper()
+	var appErr errors.AppError
+	if !errors.Is(err, kind) || !errors.As(err, &appErr) || appErr.Msg != message {
+		t.Errorf("expected %v %q; got %v", kind, message, err)
+	}
+}
+
+func TestUserRepositoryUniqueViolations(t *testing.T) {
+	repo := NewUserRepository(openTestDB(t))
+	alice := newTestUser(t, repo, "alice")
+
+	dup, _ := model.NewUser("alice", "other@example.com", "supersecret")
+	assertAppError(t, repo.Create(context.Background(), dup), errors.ErrAlreadyExists, "A user with this username already exists")
+
+	dup, _ = model.NewUser("other", "alice@example.com", "supersecret")
+	assertAppError(t, repo.Create(context.Background(), dup), errors.ErrAlreadyExists, "A user with this email already exists")
+
+	if err := repo.Delete(context.Background(), alice.ID, alice.Version); err != nil {
+		t.Fatal(err)
+	}
+	newTestUser(t, repo, "alice")
+	assertAppError(t, repo.Restore(context.Background(), alice.ID), errors.ErrAlreadyExists, "Another user has taken this username or email")
+}
+
+func TestChannelRepositoryUniqueViolations(t *testing.T) {
+	db := openTestDB(t)
+	owner := newTestUser(t, NewUserRepository(db), "owner")
+	repo := NewChannelRepository(db)
+
+	channel, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityPublic)
+	if err := repo.Create(context.Background(), channel); err != nil {
+		t.Fatal(err)
+	}
+	dup, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityPublic)
+	assertAppError(t, repo.Create(context.Background(), dup), errors.ErrAlreadyExists, "A channel with this name already exists")
+
+	if err := repo.AddUser(context.Background(), channel.ID, owner.ID, model.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	assertAppError(t, repo.AddUser(context.Background(), channel.ID, owner.ID, model.RoleMember), errors.ErrAlreadyExists, "User is already a member of the channel")
+}
+
+// TestConcurrentDeletes replays two clients that both fetched a row at the
+// same version and then delete it: only the first delete may succeed.
+func TestConcurrentDeletes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	owner := newTestUser(t, users, "owner")
+	if err := users.Delete(ctx, owner.ID, owner.Version+1); !errors.Is(err, errors.ErrPreconditionFailed) {
+		t.Errorf("expected deleting a stale user to fail; got %v", err)
+	}
+
+	channels := NewChannelRepository(db)
+	channel, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityPublic)
+	if err := channels.Create(ctx, channel); err != nil {
+		t.Fatal(err)
+	}
+	messages := NewChannelMessageRepository(db)
+	message, _ := model.NewChannelMessage(channel.ID, owner.ID, "hello")
+	if err := messages.Create(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []struct {
+		name string
+		del  func() error
+	}{
+		{"Message", func() error { return messages.Delete(ctx, channel.ID, message.ID, message.Version) }},
+		{"Channel", func() error { return channels.Delete(ctx, channel.ID, channel.Version) }},
+		{"User", func() error { return users.Delete(ctx, owner.ID, owner.Version) }},
+	} {
+		if err := d.del(); err != nil {
+			t.Fatalf("deleting the %s: %v", d.name, err)
+		}
+		assertAppError(t, d.del(), errors.ErrPreconditionFailed, d.name+" was modified concurrently")
+	}
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	owner := newTestUser(t, NewUserRepository(db), "owner")
+	channels := NewChannelRepository(db)
+	channel, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityInviteOnly)
+	if err := channels.Create(ctx, channel); err != nil {
+		t.Fatal(err)
+	}
+	if err := channels.AddUser(ctx, channel.ID, owner.ID, model.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	invites := NewChannelInviteRepository(db)
+	invite := &model.ChannelInvite{ChannelID: channel.ID, InviterID: owner.ID, Code: "code", MaxUses: 1, Status: model.InviteStatusPending}
+	if err := invites.Create(ctx, invite); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accepting the invite as a user who joined in the meantime must not
+	// spend its only use.
+	err := NewTransactor(db).Transaction(ctx, func(ctx context.Context, tx Tx) error {
+		if consumed, err := tx.ChannelInvites().ConsumeUse(ctx, invite.ID); err != nil || !consumed {
+			t.Fatalf("expected the use to be consumed; got %v, %v", consumed, err)
+		}
+		return tx.Channels().AddUser(ctx, channel.ID, owner.ID, model.RoleMember)
+	})
+	assertAppError(t, err, errors.ErrAlreadyExists, "User is already a member of the channel")
+
+	stored, err := invites.GetByID(ctx, channel.ID, invite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Uses != 0 {
+		t.Errorf("expected the use to be rolled back; got %d uses", stored.Uses)
+	}
+}
+
+func TestConsumeUseRefusesSpentInvites(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	owner := newTestUser(t, NewUserRepository(db), "owner")
+	channel, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityInviteOnly)
+	if err := NewChannelRepository(db).Create(ctx, channel); err != nil {
+		t.Fatal(err)
+	}
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	invites := NewChannelInviteRepository(db)
+	for _, c := range []struct {
+		name   string
+		invite model.ChannelInvite
+		want   bool
+	}{
+		{"usable", model.ChannelInvite{Status: model.InviteStatusPending, ExpiresAt: &future}, true},
+		{"revoked", model.ChannelInvite{Status: model.InviteStatusRevoked}, false},
+		{"expired", model.ChannelInvite{Status: model.InviteStatusPending, ExpiresAt: &past}, false},
+		{"used up", model.ChannelInvite{Status: model.InviteStatusPending, MaxUses: 1, Uses: 1}, false},
+	} {
+		invite := c.invite
+		invite.ChannelID, invite.InviterID, invite.Code = channel.ID, owner.ID, c.name
+		if err := invites.Create(ctx, &invite); err != nil {
+			t.Fatal(err)
+		}
+		if consumed, err := invites.ConsumeUse(ctx, invite.ID); err != nil || consumed != c.want {
+			t.Errorf("%s: expected consumed=%v; got %v, %v", c.name, c.want, consumed, err)
+		}
+	}
+}
+
+// TestConcurrentRefreshes replays two refreshes that both read the session
+// under the same refresh token: only the first may rotate it.
+func TestConcurrentRefreshes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	user := newTestUser(t, NewUserRepository(db), "alice")
+	sessions := NewSessionRepository(db)
+	session := &model.Session{UserID: user.ID, RefreshTokenHash: "old", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, hash := range []string{"first", "second"} {
+		rotation := *session
+		rotation.RefreshTokenHash = hash
+		rotated, err := sessions.Rotate(ctx, &rotation, "old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rotated != (i == 0) {
+			t.Errorf("refresh %d: expected rotated to be %v", i+1, i == 0)
+		}
+	}
+
+	stored, err := sessions.GetByID(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RefreshTokenHash != "first" {
+		t.Errorf("expected the first refresh's token to stand; got %q", stored.RefreshTokenHash)
+	}
+}
+
+func TestSearchRepositoryFollowsEdits(t *testing.T) {
+	db := openTestDB(t)
+	owner := newTestUser(t, NewUserRepository(db), "owner")
+	channel, _ := model.NewChannel(owner.ID, "general", "", model.VisibilityPublic)
+	if err := NewChannelRepository(db).Create(context.Background(), channel); err != nil {
+		t.Fatal(err)
+	}
+	messages := NewChannelMessageRepository(db)
+	message, _ := model.NewChannelMessage(channel.ID, owner.ID, "the quick brown fox")
+	if err := messages.Create(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSearchRepository(db)
+	search := func(text string) []*model.MessageSearchHit {
+		t.Helper()
+		result, err := repo.SearchMessages(context.Background(), owner.ID, false, model.MessageSearch{Text: text}, pagination.Page{Limit: 10})
+		if errors.Is(err, errors.ErrUnavailable) {
+			t.Skip("message search is unavailable; run the tests with -tags sqlite_fts5")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Items
+	}
+
+	if hits := search("quick"); len(hits) != 1 || hits[0].Snippet != "the <mark>quick</mark> brown fox" {
+		t.Fatalf("expected the message with a highlighted snippet; got %+v", hits)
+	}
+
+	message.Content = "the lazy dog"
+	if err := messages.Update(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	if hits := search("quick"); len(hits) != 0 {
+		t.Errorf("expected the edited out words not to match; got %+v", hits)
+	}
+	if hits := search("laz*"); len(hits) != 1 {
+		t.Errorf("expected the edited in words to match; got %+v", hits)
+	}
+
+	if err := messages.Delete(context.Background(), channel.ID, message.ID, message.Version); err != nil {
+		t.Fatal(err)
+	}
+	if hits := search("lazy"); len(hits) != 0 {
+		t.Errorf("expected a deleted message not to match; got %+v", hits)
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	for text, want := range map[string]string{
+		`hello world`:        `"hello" "world"`,
+		`"hello world" foo*`: `"hello world" "foo"*`,
+		`cats OR dogs OR`:    `"cats" OR "dogs"`,
+		`NEAR( -a: ^b "open`: `"NEAR(" "-a:" "^b" "open"`,
+		`* ""`:               ``,
+		`say it"s`:           `"say" "it""s"`,
+	} {
+		if got := ftsQuery(text); got != want {
+			t.Errorf("ftsQuery(%q) = %q; want %q", text, got, want)
+		}
+	}
+}
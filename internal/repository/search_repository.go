@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type SearchRepository interface {
+	// SearchMessages finds the messages matching search that readerID may
+	// read: those of public channels and of the other channels readerID is a
+	// member of, or of every channel when allChannels is set, and readerID's
+	// own private messages. Private messages are left out when the search is
+	// narrowed to a channel. Hits are ordered by relevance, then newest
+	// first, and are paged by offset only.
+	SearchMessages(ctx context.Context, readerID domain.EntityID, allChannels bool, search model.MessageSearch, page pagination.Page) (*pagination.Result[*model.MessageSearchHit], error)
+}
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// Snippets are built with these private use characters around the matches,
+// which cannot clash with the HTML they are replaced with once the snippet
+// has been escaped.
+const (
+	markStart = "\uE000"
+	markEnd   = "\uE001"
+)
+
+// searchDialect holds the parts of a message search that differ between
+// databases. In each, {table} stands for the messages table, aliased m, and
+// {index} for its SQLite search index.
+type searchDialect struct {
+	// from joins the messages to what matches them.
+	from string
+	// match keeps the messages matching the query.
+	match string
+	// snippet excerpts a message around its matches.
+	snippet string
+	// score rates a message's relevance, higher being more relevant.
+	score string
+}
+
+var searchDialects = map[string]searchDialect{
+	database.DriverSQLite: {
+		from:    "{index} JOIN {table} m ON m.id = {index}.message_id",
+		match:   "{index} MATCH @query",
+		snippet: "snippet({index}, 0, @mark_start, @mark_end, '…', 16)",
+		score:   "-bm25({index})",
+	},
+	database.DriverPostgres: {
+		from:    "{table} m CROSS JOIN websearch_to_tsquery('simple', @query) AS q(query)",
+		match:   "to_tsvector('simple', m.content) @@ q.query",
+		snippet: "ts_headline('simple', m.content, q.query, @headline)",
+		score:   "ts_rank(to_tsvector('simple', m.content), q.query)",
+	},
+}
+
+// hits returns the query of the hits among the messages in table, selecting
+// kind and the given channel and receiver columns, up to and including the
+// start of its WHERE clause.
+func (d searchDialect) hits(table, index, kind, channelID, receiverID, joins string) string {
+	r := strings.NewReplacer("{table}", table, "{index}", index)
+	return r.Replace(`
+		SELECT '` + kind + `' AS kind, m.id, ` + channelID + ` AS channel_id, m.sender_id,
+			` + receiverID + ` AS receiver_id, m.content, m.created_at,
+			` + d.snippet + ` AS snippet, ` + d.score + ` AS score
+		FROM ` + d.from + joins + `
+		WHERE ` + d.match + ` AND m.deleted_at IS NULL`)
+}
+
+func (r *searchRepository) SearchMessages(ctx context.Context, readerID domain.EntityID, allChannels bool, search model.MessageSearch, page pagination.Page) (*pagination.Result[*model.MessageSearchHit], error) {
+	db, span := startSpan(ctx, r.db, "SearchRepository.SearchMessages")
+	defer span.End()
+
+	driver := db.Dialector.Name()
+	dialect, ok := searchDialects[driver]
+	if driver == database.DriverSQLite && !db.Migrator().HasTable("channel_messages_fts") {
+		ok = false
+	}
+	if !ok {
+		return nil, errors.NewAppError(errors.ErrUnavailable, "Message search is not available")
+	}
+
+	query := search.Text
+	if driver == database.DriverSQLite {
+		query = ftsQuery(search.Text)
+	}
+	if strings.TrimSpace(query) == "" {
+		return pagination.NewResult([]*model.MessageSearchHit{}, page, nil), nil
+	}
+	args := map[string]any{
+		"query":      query,
+		"reader":     readerID.String(),
+		"all":        allChannels,
+		"mark_start": markStart,
+		"mark_end":   markEnd,
+		"headline":   "StartSel=" + markStart + ", StopSel=" + markEnd + ", MaxWords=24, MinWords=8",
+		"limit":      page.Limit + 1,
+		"offset":     page.Offset,
+	}
+	filters := searchFilters(search, args)
+
+	sql := dialect.hits("channel_messages", "channel_messages_fts", model.MessageKindChannel, "m.channel_id", "NULL",
+		" JOIN channels c ON c.id = m.channel_id AND c.deleted_at IS NULL") + filters + `
+			AND (@all OR c.visibility = 'public' OR EXISTS (
+				SELECT 1 FROM user_channels uc WHERE uc.channel_id = c.id AND uc.user_id = @reader
+			))`
+	if !search.ChannelID.IsZero() {
+		args["channel"] = search.ChannelID.String()
+		sql += " AND m.channel_id = @channel"
+	} else {
+		sql += `
+		UNION ALL` + dialect.hits("private_messages", "private_messages_fts", model.MessageKindPrivate, "NULL", "m.receiver_id", "") +
+			filters + `
+			AND (m.sender_id = @reader OR m.receiver_id = @reader)`
+	}
+	sql = "SELECT * FROM (" + sql + `
+		) hits
+		ORDER BY score DESC, created_at DESC, id DESC
+		LIMIT @limit OFFSET @offset`
+
+	var hits []*model.MessageSearchHit
+	if err := db.Raw(sql, args).Scan(&hits).Error; err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to search messages", err)
+	}
+	for _, hit := range hits {
+		hit.Snippet = highlight(hit.Snippet)
+	}
+	return pagination.NewResult(hits, page, nil), nil
+}
+
+// searchFilters returns the conditions, on messages aliased m, of the filters
+// of search, adding their arguments to args.
+func searchFilters(search model.MessageSearch, args map[string]any) string {
+	var sb strings.Builder
+	if !search.SenderID.IsZero() {
+		args["sender"] = search.SenderID.String()
+		sb.WriteString(" AND m.sender_id = @sender")
+	}
+	// Timestamps are written in the server's local time zone, so compare in
+	// the same zone.
+	if search.After != nil {
+		args["after"] = search.After.Local()
+		sb.WriteString(" AND m.created_at >= @after")
+	}
+	if search.Before != nil {
+		args["before"] = search.Before.Local()
+		sb.WriteString(" AND m.created_at < @before")
+	}
+	if search.HasLink {
+		sb.WriteString(" AND (m.content LIKE '%http://%' OR m.content LIKE '%https://%')")
+	}
+	return sb.String()
+}
+
+// ftsQuery turns search text into an FTS5 query that cannot be a syntax
+// error: every word and "quoted phrase" becomes a quoted string, a trailing *
+// makes it a prefix and OR between two terms is kept. All other terms are
+// required.
+func ftsQuery(text string) string {
+	var terms []string
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		var term string
+		if rest, ok := strings.CutPrefix(text, `"`); ok {
+			phrase, after, found := strings.Cut(rest, `"`)
+			if !found {
+				phrase, after = rest, ""
+			}
+			term, text = phrase, after
+		} else {
+			end := strings.IndexFunc(text, unicode.IsSpace)
+			if end < 0 {
+				end = len(text)
+			}
+			term, text = text[:end], text[end:]
+		}
+
+		if term == "OR" {
+			if len(terms) > 0 && terms[len(terms)-1] != "OR" {
+				terms = append(terms, term)
+			}
+			continue
+		}
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.TrimRight(term, "*")
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		quoted := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			quoted += "*"
+		}
+		terms = append(terms, quoted)
+	}
+	if len(terms) > 0 && terms[len(terms)-1] == "OR" {
+		terms = terms[:len(terms)-1]
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlight escapes a snippet for HTML and wraps its matches in <mark>.
+func highlight(snippet string) string {
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id domain.EntityID) (*model.Session, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*model.Session, error)
+	Update(ctx context.Context, session *model.Session) error
+	// Rotate saves the session's new refresh token hash and expiry, provided
+	// the session is still active under previousHash. It reports false when
+	// the token was rotated or revoked in the meantime.
+	Rotate(ctx context.Context, session *model.Session, previousHash string) (bool, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	db, span := startSpan(ctx, r.db, "SessionRepository.Create")
+	defer span.End()
+
+	err := db.Create(session).Error
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return errors.NewAppError(errors.ErrNotFound, "User not found")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to create session", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id domain.EntityID) (*model.Session, error) {
+	db, span := startSpan(ctx, r.db, "SessionRepository.GetByID")
+	defer span.End()
+
+	var session model.Session
+	err := db.First(&session, "id = ?", id.String()).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Session not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get session", err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetByRefreshTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	db, span := startSpan(ctx, r.db, "SessionRepository.GetByRefreshTokenHash")
+	defer span.End()
+
+	var session model.Session
+	err := db.Where("refresh_token_hash = ?", hash).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewAppError(errors.ErrNotFound, "Session not found")
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get session", err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) Update(ctx context.Context, session *model.Session) error {
+	db, span := startSpan(ctx, r.db, "SessionRepository.Update")
+	defer span.End()
+
+	err := db.Save(session).Error
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "Failed to update session", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, session *model.Session, previousHash string) (bool, error) {
+	db, span := startSpan(ctx, r.db, "SessionRepository.Rotate")
+	defer span.End()
+
+	result := db.Model(session).
+		Where("refresh_token_hash = ? AND revoked_at IS NULL", previousHash).
+		Updates(map[string]any{"refresh_token_hash": session.RefreshTokenHash, "expires_at": session.ExpiresAt})
+	if result.Error != nil {
+		return false, errors.Wrap(errors.ErrInternal, "Failed to refresh session", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
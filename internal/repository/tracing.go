@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var tracer = otel.Tracer("github.com/ruslanguns/go-chat/internal/repository")
+
+// startSpan starts the span of a repository method and returns db bound to
+// the span's context, so the method's queries are traced as its children.
+func startSpan(ctx context.Context, db *gorm.DB, name string) (*gorm.DB, trace.Span) {
+	ctx, span := tracer.Start(ctx, name)
+	return db.WithContext(ctx), span
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs work that must succeed or fail as a whole in a database
+// transaction.
+type Transactor interface {
+	// Transaction calls fn with repositories bound to a new transaction,
+	// which is committed when fn returns nil and rolled back otherwise. fn
+	// should use the context it is given, which carries the transaction's
+	// span.
+	Transaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+}
+
+// Tx gives the repositories of a transaction.
+type Tx struct {
+	db *gorm.DB
+}
+
+func (tx Tx) Channels() ChannelRepository {
+	return NewChannelRepository(tx.db)
+}
+
+func (tx Tx) ChannelInvites() ChannelInviteRepository {
+	return NewChannelInviteRepository(tx.db)
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) Transaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	ctx, span := tracer.Start(ctx, "Transaction")
+	defer span.End()
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, Tx{db: tx})
+	})
+}
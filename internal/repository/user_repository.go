@@ -1,22 +1,28 @@
 package repository
 
 import (
-	"strings"
+	"context"
+	"database/sql"
 
 	"github.com/ruslanguns/go-chat/internal/domain"
 	"github.com/ruslanguns/go-chat/internal/domain/model"
 	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	Create(user *model.User) error
-	GetByID(id domain.EntityID) (*model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByEmail(email string) (*model.User, error)
-	Update(user *model.User) error
-	Delete(id domain.EntityID) error
-	List(offset, limit int) ([]*model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id domain.EntityID) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+	// Delete soft-deletes the user, provided it is still at the given version.
+	Delete(ctx context.Context, id domain.EntityID, version int64) error
+	List(ctx context.Context, page pagination.Page) (*pagination.Result[*model.User], error)
+	ListWithDeleted(ctx context.Context, scope DeletedScope, page pagination.Page) (*pagination.Result[*model.User], error)
+	Restore(ctx context.Context, id domain.EntityID) error
+	Purge(ctx context.Context, id domain.EntityID) error
 }
 
 type userRepository struct {
@@ -27,80 +33,200 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *model.User) error {
-	err := r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	db, span := startSpan(ctx, r.db, "UserRepository.Create")
+	defer span.End()
+
+	err := db.Create(user).Error
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
+		if isUniqueViolation(err, "username") {
 			return errors.NewAppError(errors.ErrAlreadyExists, "A user with this username already exists")
 		}
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
+		if isUniqueViolation(err, "email") {
 			return errors.NewAppError(errors.ErrAlreadyExists, "A user with this email already exists")
 		}
-		return errors.NewAppError(errors.ErrInternal, err.Error())
+		return errors.Wrap(errors.ErrInternal, "Failed to create user", err)
 	}
 	return nil
 }
 
-func (r *userRepository) GetByID(id domain.EntityID) (*model.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id domain.EntityID) (*model.User, error) {
+	db, span := startSpan(ctx, r.db, "UserRepository.GetByID")
+	defer span.End()
+
 	var user model.User
-	err := r.db.First(&user, "id = ?", id.String()).Error
+	err := db.First(&user, "id = ?", id.String()).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NewAppError(errors.ErrNotFound, "User not found")
 		}
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get user")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get user", err)
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	db, span := startSpan(ctx, r.db, "UserRepository.GetByUsername")
+	defer span.End()
+
 	var user model.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := db.Where("username = ?", username).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NewAppError(errors.ErrNotFound, "User not found")
 		}
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get user")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get user", err)
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*model.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	db, span := startSpan(ctx, r.db, "UserRepository.GetByEmail")
+	defer span.End()
+
 	var user model.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := db.Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.NewAppError(errors.ErrNotFound, "User not found")
 		}
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to get user")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to get user", err)
 	}
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *model.User) error {
-	err := r.db.Save(user).Error
+// Update saves the user if it has not been changed since it was loaded,
+// returning ErrPreconditionFailed otherwise.
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	db, span := startSpan(ctx, r.db, "UserRepository.Update")
+	defer span.End()
+
+	updated, err := updateVersioned(db, user, &user.BaseEntity)
 	if err != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to update user")
+		if isUniqueViolation(err, "username") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "A user with this username already exists")
+		}
+		if isUniqueViolation(err, "email") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "A user with this email already exists")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to update user", err)
+	}
+	if !updated {
+		return errors.NewAppError(errors.ErrPreconditionFailed, "User was modified concurrently")
 	}
 	return nil
 }
 
-func (r *userRepository) Delete(id domain.EntityID) error {
-	result := r.db.Delete(&model.User{}, "id = ?", id.String())
+func (r *userRepository) Delete(ctx context.Context, id domain.EntityID, version int64) error {
+	db, span := startSpan(ctx, r.db, "UserRepository.Delete")
+	defer span.End()
+
+	result := db.Where("version = ?", version).Delete(&model.User{}, "id = ?", id.String())
 	if result.Error != nil {
-		return errors.NewAppError(errors.ErrInternal, "Failed to delete user")
+		return errors.Wrap(errors.ErrInternal, "Failed to delete user", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.NewAppError(errors.ErrNotFound, "User not found")
+		return errors.NewAppError(errors.ErrPreconditionFailed, "User was modified concurrently")
 	}
 	return nil
 }
 
-func (r *userRepository) List(offset, limit int) ([]*model.User, error) {
+func (r *userRepository) List(ctx context.Context, page pagination.Page) (*pagination.Result[*model.User], error) {
+	db, span := startSpan(ctx, r.db, "UserRepository.List")
+	defer span.End()
+
 	var users []*model.User
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err := paginate(db, page, "created_at", "id", false).Find(&users).Error
 	if err != nil {
-		return nil, errors.NewAppError(errors.ErrInternal, "Failed to list users")
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list users", err)
 	}
-	return users, nil
+	return pagination.NewResult(users, page, userCursor), nil
+}
+
+func userCursor(u *model.User) pagination.Cursor {
+	return entityCursor(&u.BaseEntity)
+}
+
+func (r *userRepository) ListWithDeleted(ctx context.Context, scope DeletedScope, page pagination.Page) (*pagination.Result[*model.User], error) {
+	db, span := startSpan(ctx, r.db, "UserRepository.ListWithDeleted")
+	defer span.End()
+
+	var users []*model.User
+	err := paginate(withDeleted(db, scope), page, "created_at", "id", false).Find(&users).Error
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to list users", err)
+	}
+	return pagination.NewResult(users, page, userCursor), nil
+}
+
+// Restore undeletes a soft-deleted user. It fails with ErrAlreadyExists when
+// a live user has taken the username or email in the meantime.
+func (r *userRepository) Restore(ctx context.Context, id domain.EntityID) error {
+	db, span := startSpan(ctx, r.db, "UserRepository.Restore")
+	defer span.End()
+
+	restored, err := restoreDeleted(db, &model.User{}, id.String())
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			return errors.NewAppError(errors.ErrAlreadyExists, "Another user has taken this username or email")
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to restore user", err)
+	}
+	if !restored {
+		return errors.NewAppError(errors.ErrNotFound, "Deleted user not found")
+	}
+	return nil
+}
+
+// Purge permanently removes a soft-deleted user along with their channel
+// memberships, messages, sessions and invites. Users who still own channels,
+// deleted or not, cannot be purged.
+func (r *userRepository) Purge(ctx context.Context, id domain.EntityID) error {
+	db, span := startSpan(ctx, r.db, "UserRepository.Purge")
+	defer span.End()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		err := tx.Unscoped().First(&user, "id = ? AND deleted_at IS NOT NULL", id.String()).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.NewAppError(errors.ErrNotFound, "Deleted user not found")
+			}
+			return err
+		}
+
+		var owned int64
+		if err := tx.Unscoped().Model(&model.Channel{}).Where("owner_id = ?", id.String()).Count(&owned).Error; err != nil {
+			return err
+		}
+		if owned > 0 {
+			return errors.NewAppError(errors.ErrConflict, "User still owns channels; purge them first")
+		}
+
+		cascade := []struct {
+			model any
+			query string
+		}{
+			{&model.ChannelMember{}, "user_id = @id"},
+			{&model.ChannelMessage{}, "sender_id = @id"},
+			{&model.PrivateMessage{}, "sender_id = @id OR receiver_id = @id"},
+			{&model.Session{}, "user_id = @id"},
+			{&model.ChannelInvite{}, "inviter_id = @id OR invitee_id = @id"},
+		}
+		for _, c := range cascade {
+			if err := tx.Unscoped().Where(c.query, sql.Named("id", id.String())).Delete(c.model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&user).Error
+	})
+	if err != nil {
+		var appErr errors.AppError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
+		return errors.Wrap(errors.ErrInternal, "Failed to purge user", err)
+	}
+	return nil
 }
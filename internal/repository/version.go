@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"gorm.io/gorm"
+)
+
+// updateVersioned saves value, whose embedded BaseEntity is base, only if
+// the stored row still has base's version, and bumps the version. It reports
+// false when the row was changed or removed in the meantime.
+func updateVersioned(db *gorm.DB, value any, base *domain.BaseEntity) (bool, error) {
+	expected := base.Version
+	base.Version++
+
+	result := db.Model(value).Where("version = ?", expected).Select("*").Updates(value)
+	if result.Error != nil || result.RowsAffected == 0 {
+		base.Version = expected
+		return false, result.Error
+	}
+	return true, nil
+}
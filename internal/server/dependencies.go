@@ -0,0 +1,115 @@
+package server
+
+import (
+	"github.com/ruslanguns/go-chat/internal/auth"
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/ratelimit"
+	"github.com/ruslanguns/go-chat/internal/realtime"
+	"github.com/ruslanguns/go-chat/internal/repository"
+	"github.com/ruslanguns/go-chat/internal/service"
+)
+
+// Dependencies are the parts the server is wired from. NewServer builds any
+// left nil from the database and configuration; services built that way use
+// the repositories, realtime transports and metrics registry given here.
+type Dependencies struct {
+	Users           repository.UserRepository
+	Channels        repository.ChannelRepository
+	ChannelMessages repository.ChannelMessageRepository
+	PrivateMessages repository.PrivateMessageRepository
+	Sessions        repository.SessionRepository
+	ChannelInvites  repository.ChannelInviteRepository
+	Search          repository.SearchRepository
+	Transactor      repository.Transactor
+
+	Hub        *realtime.Hub
+	Stream     *realtime.Stream
+	RateLimits ratelimit.Store
+	Metrics    metrics.Registry
+
+	AuthService           service.AuthService
+	UserService           service.UserService
+	ChannelService        service.ChannelService
+	ChannelMessageService service.ChannelMessageService
+	PrivateMessageService service.PrivateMessageService
+	ChannelInviteService  service.ChannelInviteService
+	AdminService          service.AdminService
+	SearchService         service.SearchService
+}
+
+// Option substitutes some of the server's dependencies by setting their
+// fields.
+type Option func(*Dependencies)
+
+// withDefaults fills in the dependencies left nil with the default wiring.
+func (d Dependencies) withDefaults(cfg *config.Config, db database.Service) Dependencies {
+	gormDB := db.GetDB()
+	if d.Users == nil {
+		d.Users = repository.NewUserRepository(gormDB)
+	}
+	if d.Channels == nil {
+		d.Channels = repository.NewChannelRepository(gormDB)
+	}
+	if d.ChannelMessages == nil {
+		d.ChannelMessages = repository.NewChannelMessageRepository(gormDB)
+	}
+	if d.PrivateMessages == nil {
+		d.PrivateMessages = repository.NewPrivateMessageRepository(gormDB)
+	}
+	if d.Sessions == nil {
+		d.Sessions = repository.NewSessionRepository(gormDB)
+	}
+	if d.ChannelInvites == nil {
+		d.ChannelInvites = repository.NewChannelInviteRepository(gormDB)
+	}
+	if d.Search == nil {
+		d.Search = repository.NewSearchRepository(gormDB)
+	}
+	if d.Transactor == nil {
+		d.Transactor = repository.NewTransactor(gormDB)
+	}
+
+	if d.Hub == nil {
+		d.Hub = realtime.NewHub()
+	}
+	if d.Stream == nil {
+		d.Stream = realtime.NewStream()
+	}
+	if d.RateLimits == nil {
+		d.RateLimits = ratelimit.NewMemoryStore()
+	}
+	if d.Metrics == nil {
+		d.Metrics = metrics.NewRegistry()
+	}
+
+	publisher := realtime.NewPublisher(d.Hub, d.Stream)
+	authorizer := service.NewAuthorizer()
+	if d.AuthService == nil {
+		tokenIssuer := auth.NewTokenIssuer(authSecret(cfg.Auth), cfg.Auth.AccessTokenTTL)
+		d.AuthService = service.NewAuthService(d.Users, d.Sessions, tokenIssuer)
+	}
+	if d.UserService == nil {
+		d.UserService = service.NewUserService(d.Users, authorizer)
+	}
+	if d.ChannelService == nil {
+		d.ChannelService = service.NewChannelService(d.Channels, d.Users, d.Transactor, publisher, authorizer, d.Metrics)
+	}
+	if d.ChannelMessageService == nil {
+		d.ChannelMessageService = service.NewChannelMessageService(d.ChannelMessages, d.Channels, publisher, authorizer, d.Metrics)
+	}
+	if d.PrivateMessageService == nil {
+		d.PrivateMessageService = service.NewPrivateMessageService(d.PrivateMessages, d.Users, publisher, authorizer, d.Metrics)
+	}
+	if d.ChannelInviteService == nil {
+		d.ChannelInviteService = service.NewChannelInviteService(d.ChannelInvites, d.Channels, d.Users, d.Transactor, publisher, authorizer, d.Metrics)
+	}
+	if d.AdminService == nil {
+		d.AdminService = service.NewAdminService(d.Users, d.Channels, authorizer)
+	}
+	if d.SearchService == nil {
+		d.SearchService = service.NewSearchService(d.Search, d.Channels, authorizer)
+	}
+	return d
+}
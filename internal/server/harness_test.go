@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+)
+
+// testApp is the full HTTP stack served over a private in-memory SQLite
+// database, or over the database TEST_DB_DRIVER and TEST_DB_URL point to,
+// emptied first.
+type testApp struct {
+	t      *testing.T
+	db     database.Service
+	srv    *Server
+	server *httptest.Server
+}
+
+// newTestApp serves the app with the default configuration, adjusted by
+// configure. Rate limiting is off unless configure turns it on, so that tests
+// may sign up as many users as they need.
+func newTestApp(t *testing.T, configure ...func(*config.Config)) *testApp {
+	t.Helper()
+
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_URL")
+	shared := dsn != ""
+	if !shared {
+		// Every connection to a named shared-cache memory database sees the
+		// same data, and the database lives as long as the connection pool.
+		driver, dsn = database.DriverSQLite, fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	}
+	db, err := database.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("opening %s database: %v", driver, err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	if shared {
+		// Children before parents, as in the repository tests.
+		for _, table := range []string{"sessions", "channel_messages", "channel_invites", "user_channels", "channels", "private_messages", "users"} {
+			if err := db.GetDB().Exec("DELETE FROM " + table).Error; err != nil {
+				t.Fatalf("emptying %s: %v", table, err)
+			}
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Auth.Secret = "integration-test-secret"
+	cfg.RateLimit.Enabled = false
+	for _, f := range configure {
+		f(&cfg)
+	}
+	srv := NewServer(&cfg, db)
+	server := httptest.NewServer(srv.Handler())
+
+	t.Cleanup(func() {
+		server.Close()
+		db.Close()
+	})
+	return &testApp{t: t, db: db, srv: srv, server: server}
+}
+
+// testResponse is a response with its body read.
+type testResponse struct {
+	*http.Response
+	body []byte
+}
+
+// request sends a request with an optional JSON body, authenticated with
+// token unless it is empty. headers are given as name, value pairs.
+func (a *testApp) request(method, path, token string, body any, headers ...string) *testResponse {
+	a.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.server.URL+path, reader)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := a.server.Client().Do(req)
+	if err != nil {
+		a.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return &testResponse{Response: resp, body: data}
+}
+
+// expect sends a request like request and fails the test unless the
+// response has the given status. The response body is decoded into out when
+// it is not nil.
+func (a *testApp) expect(status int, method, path, token string, body, out any, headers ...string) *testResponse {
+	a.t.Helper()
+	resp := a.request(method, path, token, body, headers...)
+	if resp.StatusCode != status {
+		a.t.Fatalf("%s %s: expected status %d; got %d: %s", method, path, status, resp.StatusCode, resp.body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.body, out); err != nil {
+			a.t.Fatalf("%s %s: decoding %s: %v", method, path, resp.body, err)
+		}
+	}
+	return resp
+}
+
+// testUser is a signed-up user with a valid access token.
+type testUser struct {
+	ID           string
+	Token        string
+	RefreshToken string
+}
+
+func (a *testApp) signup(username string) testUser {
+	a.t.Helper()
+	var user struct {
+		ID string `json:"id"`
+	}
+	a.expect(http.StatusCreated, http.MethodPost, "/users", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "supersecret",
+	}, &user)
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	a.expect(http.StatusOK, http.MethodPost, "/auth/login", "", map[string]string{
+		"username": username,
+		"password": "supersecret",
+	}, &tokens)
+	return testUser{ID: user.ID, Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken}
+}
+
+// signupAdmin signs up a user and grants them platform administration.
+func (a *testApp) signupAdmin(username string) testUser {
+	a.t.Helper()
+	user := a.signup(username)
+	if err := a.db.GetDB().Exec("UPDATE users SET is_admin = ? WHERE id = ?", true, user.ID).Error; err != nil {
+		a.t.Fatal(err)
+	}
+	return user
+}
+
+// createChannel creates a channel owned by owner and returns its ID.
+func (a *testApp) createChannel(owner testUser, name, visibility string) string {
+	a.t.Helper()
+	var channel struct {
+		ID string `json:"id"`
+	}
+	a.expect(http.StatusCreated, http.MethodPost, "/channels", owner.Token, map[string]string{
+		"name":       name,
+		"visibility": visibility,
+	}, &channel)
+	return channel.ID
+}
+
+// wsURL returns the websocket URL of path.
+func (a *testApp) wsURL(path string) string {
+	return "ws" + strings.TrimPrefix(a.server.URL, "http") + path
+}
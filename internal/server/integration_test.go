@@ -0,0 +1,784 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/database"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/realtime"
+	"github.com/ruslanguns/go-chat/internal/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type userBody struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Version  int64  `json:"version"`
+}
+
+type channelBody struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	OwnerID     string `json:"owner_id"`
+	Visibility  string `json:"visibility"`
+}
+
+type messageBody struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+func TestHealthAndRoot(t *testing.T) {
+	app := newTestApp(t)
+
+	var root map[string]string
+	app.expect(http.StatusOK, http.MethodGet, "/", "", nil, &root)
+	if root["message"] != "Hello World" {
+		t.Errorf("unexpected root response %v", root)
+	}
+
+	var health map[string]string
+	app.expect(http.StatusOK, http.MethodGet, "/health", "", nil, &health)
+	if health["status"] != "up" {
+		t.Errorf("expected the database to be up; got %v", health)
+	}
+}
+
+type probeBody struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	} `json:"checks"`
+}
+
+func TestProbes(t *testing.T) {
+	app := newTestApp(t)
+
+	var probe probeBody
+	app.expect(http.StatusOK, http.MethodGet, "/livez", "", nil, &probe)
+	if probe.Status != "up" {
+		t.Errorf("expected the server to be live; got %+v", probe)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/readyz", "", nil, &probe)
+	for _, name := range []string{"server", "database", "migrations", "realtime_hub", "event_stream"} {
+		if probe.Checks[name].Status != "up" {
+			t.Errorf("expected the %s check to be up; got %+v", name, probe.Checks[name])
+		}
+	}
+
+	migrator, err := app.db.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	app.expect(http.StatusServiceUnavailable, http.MethodGet, "/readyz", "", nil, &probe)
+	if probe.Status != "down" || probe.Checks["migrations"].Status != "down" {
+		t.Errorf("expected pending migrations to fail readiness; got %+v", probe)
+	}
+
+	// A broken database is reported, not fatal.
+	app.db.Close()
+	app.expect(http.StatusServiceUnavailable, http.MethodGet, "/readyz", "", nil, &probe)
+	if probe.Checks["database"].Status != "down" || probe.Checks["database"].Error == "" {
+		t.Errorf("expected the database check to fail; got %+v", probe.Checks["database"])
+	}
+	var health map[string]string
+	app.expect(http.StatusServiceUnavailable, http.MethodGet, "/health", "", nil, &health)
+	if health["status"] != "down" {
+		t.Errorf("expected the database to be down; got %v", health)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/livez", "", nil, nil)
+}
+
+func TestReadinessWhileDraining(t *testing.T) {
+	app := newTestApp(t)
+	app.srv.draining.Store(true)
+
+	var probe probeBody
+	app.expect(http.StatusServiceUnavailable, http.MethodGet, "/readyz", "", nil, &probe)
+	if probe.Checks["server"].Error != "shutting down" {
+		t.Errorf("expected the server check to report the shutdown; got %+v", probe.Checks["server"])
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/livez", "", nil, nil)
+}
+
+func TestMetrics(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	channel := app.createChannel(alice, "general", "public")
+	app.expect(http.StatusNoContent, http.MethodPost, "/channels/"+channel+"/join", bob.Token, nil, nil)
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+channel+"/messages", bob.Token,
+		map[string]string{"content": "hello"}, nil)
+	app.expect(http.StatusCreated, http.MethodPost, "/users/"+alice.ID+"/conversations/"+bob.ID+"/messages", alice.Token,
+		map[string]string{"content": "hi bob"}, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+channel, alice.Token, nil, nil)
+
+	body := string(app.expect(http.StatusOK, http.MethodGet, "/metrics", "", nil, nil).body)
+	for _, want := range []string{
+		`chat_http_requests_total{method="GET",route="/channels/{id}",status="200"} 1`,
+		`chat_http_requests_total{method="POST",route="/auth/login",status="200"} 2`,
+		`chat_http_request_duration_seconds_bucket{method="POST",route="/channels/{id}/messages",le="+Inf"} 1`,
+		`chat_messages_sent_total{kind="channel"} 1`,
+		`chat_messages_sent_total{kind="private"} 1`,
+		`chat_channels_created_total 1`,
+		`chat_channel_membership_changes_total{change="joined"} 1`,
+		`chat_websocket_connections 0`,
+		`chat_db_open_connections`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the metrics", want)
+		}
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	app := newTestApp(t)
+
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	resp := app.expect(http.StatusUnauthorized, http.MethodGet, "/auth/me", "", nil, &body)
+	if id := resp.Header.Get("X-Request-ID"); id == "" || id != body.RequestID {
+		t.Errorf("expected the X-Request-ID header %q to match the error body's request ID %q", id, body.RequestID)
+	}
+
+	resp = app.expect(http.StatusUnauthorized, http.MethodGet, "/auth/me", "", nil, &body, "X-Request-ID", "from-the-proxy")
+	if id := resp.Header.Get("X-Request-ID"); id != "from-the-proxy" || body.RequestID != "from-the-proxy" {
+		t.Errorf("expected the caller's request ID to be kept; got header %q and body %q", id, body.RequestID)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	app := newTestApp(t, func(cfg *config.Config) {
+		cfg.RateLimit.Enabled = true
+		cfg.RateLimit.Signup = config.RateLimitRule{Requests: 2, Per: time.Hour}
+		cfg.RateLimit.Messages = config.RateLimitRule{Requests: 1, Per: time.Hour}
+	})
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+
+	var body struct {
+		Code    string         `json:"code"`
+		Details map[string]any `json:"details"`
+	}
+	resp := app.expect(http.StatusTooManyRequests, http.MethodPost, "/users", "", map[string]string{
+		"username": "carol",
+		"email":    "carol@example.com",
+		"password": "supersecret",
+	}, &body)
+	if body.Code != "rate_limited" || body.Details["policy"] != "signup" {
+		t.Errorf("unexpected error body %+v", body)
+	}
+	if resp.Header.Get("Retry-After") != "1800" || resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected rate limit headers %v", resp.Header)
+	}
+
+	// Messages are limited per user rather than per address.
+	channel := app.createChannel(alice, "general", "public")
+	app.expect(http.StatusNoContent, http.MethodPost, "/channels/"+channel+"/join", bob.Token, nil, nil)
+	send := map[string]string{"content": "hello"}
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+channel+"/messages", alice.Token, send, nil)
+	app.expect(http.StatusTooManyRequests, http.MethodPost, "/users/"+alice.ID+"/conversations/"+bob.ID+"/messages", alice.Token, send, nil)
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+channel+"/messages", bob.Token, send, nil)
+
+	// Other routes are only subject to the API policy, and probes to none.
+	resp = app.expect(http.StatusOK, http.MethodGet, "/channels/"+channel+"/messages", alice.Token, nil, nil)
+	if resp.Header.Get("X-RateLimit-Limit") != "300" {
+		t.Errorf("expected the API policy's headers; got %v", resp.Header)
+	}
+	resp = app.expect(http.StatusOK, http.MethodGet, "/livez", "", nil, nil)
+	if resp.Header.Get("X-RateLimit-Limit") != "" {
+		t.Errorf("expected probes not to be rate limited; got %v", resp.Header)
+	}
+}
+
+func TestAuthRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+
+	app.expect(http.StatusUnauthorized, http.MethodGet, "/auth/me", "", nil, nil)
+	app.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login", "", map[string]string{
+		"username": "alice",
+		"password": "wrong-password",
+	}, nil)
+	// Email addresses are matched regardless of case.
+	app.expect(http.StatusOK, http.MethodPost, "/auth/login", "", map[string]string{
+		"username": " Alice@Example.COM",
+		"password": "supersecret",
+	}, nil)
+
+	var me userBody
+	app.expect(http.StatusOK, http.MethodGet, "/auth/me", alice.Token, nil, &me)
+	if me.ID != alice.ID || me.Username != "alice" {
+		t.Errorf("unexpected current user %+v", me)
+	}
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	app.expect(http.StatusOK, http.MethodPost, "/auth/refresh", "", map[string]string{
+		"refresh_token": alice.RefreshToken,
+	}, &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == alice.RefreshToken {
+		t.Errorf("expected rotated tokens; got %+v", tokens)
+	}
+
+	// The rotated refresh token cannot be used again.
+	app.expect(http.StatusUnauthorized, http.MethodPost, "/auth/refresh", "", map[string]string{
+		"refresh_token": alice.RefreshToken,
+	}, nil)
+
+	app.expect(http.StatusNoContent, http.MethodPost, "/auth/logout", tokens.AccessToken, nil, nil)
+	app.expect(http.StatusUnauthorized, http.MethodPost, "/auth/refresh", "", map[string]string{
+		"refresh_token": tokens.RefreshToken,
+	}, nil)
+}
+
+func TestUserRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+
+	app.expect(http.StatusConflict, http.MethodPost, "/users", "", map[string]string{
+		"username": "alice",
+		"email":    "other@example.com",
+		"password": "supersecret",
+	}, nil)
+	app.expect(http.StatusUnprocessableEntity, http.MethodPost, "/users", "", map[string]string{
+		"username": "carol",
+		"email":    "not-an-email",
+		"password": "short",
+	}, nil)
+
+	var users page[userBody]
+	app.expect(http.StatusOK, http.MethodGet, "/users?limit=1", alice.Token, nil, &users)
+	if len(users.Items) != 1 || !users.HasMore || users.NextCursor == "" {
+		t.Fatalf("expected the first page of two users; got %+v", users)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/users?limit=1&cursor="+users.NextCursor, alice.Token, nil, &users)
+	if len(users.Items) != 1 || users.HasMore {
+		t.Fatalf("expected the last page of two users; got %+v", users)
+	}
+
+	var user userBody
+	resp := app.expect(http.StatusOK, http.MethodGet, "/users/"+bob.ID, alice.Token, nil, &user)
+	etag := resp.Header.Get("ETag")
+	if user.Username != "bob" || etag == "" {
+		t.Fatalf("unexpected user %+v with ETag %q", user, etag)
+	}
+	app.expect(http.StatusNotModified, http.MethodGet, "/users/"+bob.ID, alice.Token, nil, nil, "If-None-Match", etag)
+
+	update := map[string]string{"username": "robert", "email": "robert@example.com"}
+	app.expect(http.StatusForbidden, http.MethodPut, "/users/"+bob.ID, alice.Token, update, nil, "If-Match", etag)
+	app.expect(http.StatusPreconditionRequired, http.MethodPut, "/users/"+bob.ID, bob.Token, update, nil)
+	resp = app.expect(http.StatusOK, http.MethodPut, "/users/"+bob.ID, bob.Token, update, &user, "If-Match", etag)
+	if user.Username != "robert" || resp.Header.Get("ETag") == etag {
+		t.Fatalf("expected the update to bump the version; got %+v", user)
+	}
+	app.expect(http.StatusPreconditionFailed, http.MethodPatch, "/users/"+bob.ID, bob.Token, map[string]string{"username": "bobby"}, nil, "If-Match", etag)
+
+	etag = resp.Header.Get("ETag")
+	app.expect(http.StatusOK, http.MethodPatch, "/users/"+bob.ID, bob.Token, map[string]string{"username": "bobby"}, &user, "If-Match", etag)
+	if user.Username != "bobby" || user.Email != "robert@example.com" {
+		t.Fatalf("expected only the username to change; got %+v", user)
+	}
+
+	app.expect(http.StatusNoContent, http.MethodDelete, "/users/"+bob.ID, bob.Token, nil, nil, "If-Match", "*")
+	app.expect(http.StatusNotFound, http.MethodGet, "/users/"+bob.ID, alice.Token, nil, nil)
+	app.expect(http.StatusBadRequest, http.MethodGet, "/users/not-an-id", alice.Token, nil, nil)
+}
+
+func TestChannelRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	carol := app.signup("carol")
+
+	general := app.createChannel(alice, "general", "public")
+	secret := app.createChannel(alice, "secret", "private")
+	app.expect(http.StatusConflict, http.MethodPost, "/channels", bob.Token, map[string]string{"name": "general"}, nil)
+
+	// Private channels are hidden from non-members.
+	var channels page[channelBody]
+	app.expect(http.StatusOK, http.MethodGet, "/channels", bob.Token, nil, &channels)
+	if len(channels.Items) != 1 || channels.Items[0].ID != general {
+		t.Fatalf("expected only the public channel; got %+v", channels.Items)
+	}
+	app.expect(http.StatusNotFound, http.MethodGet, "/channels/"+secret, bob.Token, nil, nil)
+
+	app.expect(http.StatusNoContent, http.MethodPost, "/channels/"+general+"/join", bob.Token, nil, nil)
+	app.expect(http.StatusNotFound, http.MethodPost, "/channels/"+secret+"/join", bob.Token, nil, nil)
+
+	// Only channel admins add members, and the body names the user.
+	app.expect(http.StatusUnprocessableEntity, http.MethodPost, "/channels/"+secret+"/users", alice.Token, map[string]string{}, nil)
+	app.expect(http.StatusForbidden, http.MethodPost, "/channels/"+general+"/users", bob.Token, map[string]string{"user_id": carol.ID}, nil)
+	app.expect(http.StatusNoContent, http.MethodPost, "/channels/"+secret+"/users", alice.Token, map[string]string{"user_id": bob.ID}, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+secret, bob.Token, nil, nil)
+
+	app.expect(http.StatusNoContent, http.MethodPut, "/channels/"+secret+"/users/"+bob.ID+"/role", alice.Token,
+		map[string]string{"role": "moderator"}, nil)
+	var members page[struct {
+		ID   string `json:"id"`
+		Role string `json:"role"`
+	}]
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+secret+"/users", bob.Token, nil, &members)
+	roles := make(map[string]string)
+	for _, member := range members.Items {
+		roles[member.ID] = member.Role
+	}
+	if roles[alice.ID] != "owner" || roles[bob.ID] != "moderator" {
+		t.Fatalf("unexpected channel roles %v", roles)
+	}
+	app.expect(http.StatusNoContent, http.MethodDelete, "/channels/"+secret+"/users/"+bob.ID, alice.Token, nil, nil)
+	app.expect(http.StatusNotFound, http.MethodGet, "/channels/"+secret, bob.Token, nil, nil)
+
+	var channel channelBody
+	resp := app.expect(http.StatusOK, http.MethodGet, "/channels/"+general, alice.Token, nil, &channel)
+	etag := resp.Header.Get("ETag")
+	app.expect(http.StatusForbidden, http.MethodPut, "/channels/"+general, bob.Token,
+		map[string]string{"name": "lobby"}, nil, "If-Match", etag)
+	resp = app.expect(http.StatusOK, http.MethodPut, "/channels/"+general, alice.Token,
+		map[string]string{"name": "lobby", "description": "Say hi"}, &channel, "If-Match", etag)
+	if channel.Name != "lobby" || channel.Description != "Say hi" || channel.Visibility != "public" {
+		t.Fatalf("unexpected updated channel %+v", channel)
+	}
+	app.expect(http.StatusOK, http.MethodPatch, "/channels/"+general, alice.Token,
+		map[string]string{"visibility": "invite_only"}, &channel, "If-Match", resp.Header.Get("ETag"))
+	if channel.Name != "lobby" || channel.Visibility != "invite_only" {
+		t.Fatalf("expected only the visibility to change; got %+v", channel)
+	}
+
+	app.expect(http.StatusPreconditionRequired, http.MethodDelete, "/channels/"+general, alice.Token, nil, nil)
+	app.expect(http.StatusNoContent, http.MethodDelete, "/channels/"+general, alice.Token, nil, nil, "If-Match", "*")
+	app.expect(http.StatusNotFound, http.MethodGet, "/channels/"+general, alice.Token, nil, nil)
+}
+
+func TestChannelMessageRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	channel := app.createChannel(alice, "general", "public")
+	messages := "/channels/" + channel + "/messages"
+
+	app.expect(http.StatusForbidden, http.MethodPost, messages, bob.Token, map[string]string{"content": "hi"}, nil)
+	app.expect(http.StatusNoContent, http.MethodPost, "/channels/"+channel+"/join", bob.Token, nil, nil)
+
+	var message messageBody
+	app.expect(http.StatusCreated, http.MethodPost, messages, bob.Token, map[string]string{"content": "hello"}, &message)
+	app.expect(http.StatusCreated, http.MethodPost, messages, alice.Token, map[string]string{"content": "welcome"}, nil)
+	app.expect(http.StatusUnprocessableEntity, http.MethodPost, messages, alice.Token, map[string]string{"content": ""}, nil)
+
+	var list page[messageBody]
+	app.expect(http.StatusOK, http.MethodGet, messages, bob.Token, nil, &list)
+	if len(list.Items) != 2 {
+		t.Fatalf("expected two messages; got %+v", list.Items)
+	}
+
+	resp := app.expect(http.StatusOK, http.MethodGet, messages+"/"+message.ID, alice.Token, nil, &message)
+	etag := resp.Header.Get("ETag")
+	app.expect(http.StatusForbidden, http.MethodPut, messages+"/"+message.ID, alice.Token,
+		map[string]string{"content": "edited"}, nil, "If-Match", etag)
+	resp = app.expect(http.StatusOK, http.MethodPut, messages+"/"+message.ID, bob.Token,
+		map[string]string{"content": "edited"}, &message, "If-Match", etag)
+	if message.Content != "edited" {
+		t.Fatalf("expected the message to be edited; got %+v", message)
+	}
+	app.expect(http.StatusPreconditionFailed, http.MethodDelete, messages+"/"+message.ID, bob.Token, nil, nil, "If-Match", etag)
+	app.expect(http.StatusNoContent, http.MethodDelete, messages+"/"+message.ID, bob.Token, nil, nil, "If-Match", resp.Header.Get("ETag"))
+	app.expect(http.StatusNotFound, http.MethodGet, messages+"/"+message.ID, alice.Token, nil, nil)
+
+	inviteOnly := app.createChannel(alice, "staff", "invite_only")
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+inviteOnly+"/messages", alice.Token, map[string]string{"content": "staff only"}, &message)
+	app.expect(http.StatusForbidden, http.MethodGet, "/channels/"+inviteOnly+"/messages", bob.Token, nil, nil)
+	app.expect(http.StatusForbidden, http.MethodGet, "/channels/"+inviteOnly+"/messages/"+message.ID, bob.Token, nil, nil)
+	app.expect(http.StatusForbidden, http.MethodGet, "/channels/"+inviteOnly+"/users", bob.Token, nil, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+inviteOnly+"/messages", alice.Token, nil, nil)
+}
+
+func TestInviteRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	carol := app.signup("carol")
+	channel := app.createChannel(alice, "team", "invite_only")
+	invites := "/channels/" + channel + "/invites"
+
+	app.expect(http.StatusForbidden, http.MethodPost, "/channels/"+channel+"/join", bob.Token, nil, nil)
+
+	type inviteBody struct {
+		ID     string `json:"id"`
+		Code   string `json:"code"`
+		Status string `json:"status"`
+	}
+	var forBob, forCarol, open inviteBody
+	app.expect(http.StatusCreated, http.MethodPost, invites, alice.Token, map[string]any{"user_id": bob.ID}, &forBob)
+	app.expect(http.StatusCreated, http.MethodPost, invites, alice.Token, map[string]any{"user_id": carol.ID}, &forCarol)
+	app.expect(http.StatusCreated, http.MethodPost, invites, alice.Token, map[string]any{"max_uses": 1, "expires_in": 3600}, &open)
+	app.expect(http.StatusForbidden, http.MethodPost, invites, bob.Token, map[string]any{}, nil)
+
+	var list page[inviteBody]
+	app.expect(http.StatusOK, http.MethodGet, invites, alice.Token, nil, &list)
+	if len(list.Items) != 3 {
+		t.Fatalf("expected three invites; got %+v", list.Items)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/invites", bob.Token, nil, &list)
+	if len(list.Items) != 1 || list.Items[0].Code != forBob.Code {
+		t.Fatalf("expected bob's pending invite; got %+v", list.Items)
+	}
+
+	// An invite addressed to bob cannot be used by anyone else.
+	app.expect(http.StatusNotFound, http.MethodPost, "/invites/"+forBob.Code+"/accept", carol.Token, nil, nil)
+	var joined channelBody
+	app.expect(http.StatusOK, http.MethodPost, "/invites/"+forBob.Code+"/accept", bob.Token, nil, &joined)
+	if joined.ID != channel {
+		t.Fatalf("expected to join the channel; got %+v", joined)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+channel, bob.Token, nil, nil)
+
+	app.expect(http.StatusNoContent, http.MethodPost, "/invites/"+forCarol.Code+"/decline", carol.Token, nil, nil)
+	app.expect(http.StatusConflict, http.MethodPost, "/invites/"+forCarol.Code+"/decline", carol.Token, nil, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/invites", carol.Token, nil, &list)
+	if len(list.Items) != 0 {
+		t.Fatalf("expected no pending invites after declining; got %+v", list.Items)
+	}
+
+	app.expect(http.StatusNoContent, http.MethodDelete, invites+"/"+open.ID, alice.Token, nil, nil)
+	app.expect(http.StatusConflict, http.MethodDelete, invites+"/"+open.ID, alice.Token, nil, nil)
+	app.expect(http.StatusForbidden, http.MethodPost, "/invites/"+open.Code+"/accept", carol.Token, nil, nil)
+}
+
+func TestPrivateMessageRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+
+	app.expect(http.StatusForbidden, http.MethodPost, "/users/"+bob.ID+"/conversations/"+alice.ID+"/messages", alice.Token,
+		map[string]string{"content": "spoofed"}, nil)
+	app.expect(http.StatusCreated, http.MethodPost, "/users/"+alice.ID+"/conversations/"+bob.ID+"/messages", alice.Token,
+		map[string]string{"content": "hi bob"}, nil)
+	app.expect(http.StatusCreated, http.MethodPost, "/users/"+alice.ID+"/conversations/"+bob.ID+"/messages", alice.Token,
+		map[string]string{"content": "are you there?"}, nil)
+
+	var conversations page[struct {
+		CounterpartID string      `json:"counterpart_id"`
+		LastMessage   messageBody `json:"last_message"`
+		UnreadCount   int64       `json:"unread_count"`
+	}]
+	app.expect(http.StatusOK, http.MethodGet, "/users/"+bob.ID+"/conversations", bob.Token, nil, &conversations)
+	if len(conversations.Items) != 1 || conversations.Items[0].CounterpartID != alice.ID ||
+		conversations.Items[0].LastMessage.Content != "are you there?" || conversations.Items[0].UnreadCount != 2 {
+		t.Fatalf("unexpected conversations %+v", conversations.Items)
+	}
+	cursor := pagination.Cursor{CreatedAt: time.Now(), ID: alice.ID}.String()
+	app.expect(http.StatusBadRequest, http.MethodGet, "/users/"+bob.ID+"/conversations?cursor="+cursor, bob.Token, nil, nil)
+
+	var messages page[messageBody]
+	app.expect(http.StatusOK, http.MethodGet, "/users/"+bob.ID+"/conversations/"+alice.ID+"/messages", bob.Token, nil, &messages)
+	if len(messages.Items) != 2 {
+		t.Fatalf("expected two messages; got %+v", messages.Items)
+	}
+	app.expect(http.StatusForbidden, http.MethodGet, "/users/"+bob.ID+"/conversations/"+alice.ID+"/messages", alice.Token, nil, nil)
+
+	var read map[string]int64
+	app.expect(http.StatusOK, http.MethodPost, "/users/"+bob.ID+"/conversations/"+alice.ID+"/read", bob.Token, nil, &read)
+	if read["marked_read"] != 2 {
+		t.Fatalf("expected two messages marked read; got %v", read)
+	}
+}
+
+func TestSearchRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	carol := app.signup("carol")
+
+	if app.db.GetDB().Dialector.Name() == database.DriverSQLite && !app.db.GetDB().Migrator().HasTable("channel_messages_fts") {
+		app.expect(http.StatusServiceUnavailable, http.MethodGet, "/search/messages?q=hello", alice.Token, nil, nil)
+		t.Skip("SQLite was built without FTS5; run the tests with -tags sqlite_fts5")
+	}
+
+	general := app.createChannel(alice, "general", "public")
+	secret := app.createChannel(alice, "secret", "private")
+	staff := app.createChannel(alice, "staff", "invite_only")
+	post := func(user testUser, path, content string) {
+		app.expect(http.StatusCreated, http.MethodPost, path, user.Token, map[string]string{"content": content}, nil)
+	}
+	post(alice, "/channels/"+general+"/messages", "Deploying the release today, notes at https://example.com/notes")
+	post(alice, "/channels/"+general+"/messages", "release party <tonight>")
+	post(alice, "/channels/"+secret+"/messages", "the secret release date")
+	post(alice, "/channels/"+staff+"/messages", "staff release checklist")
+	post(alice, "/users/"+alice.ID+"/conversations/"+bob.ID+"/messages", "bob, the release is out")
+
+	type hit struct {
+		Kind      string `json:"kind"`
+		ChannelID string `json:"channel_id"`
+		Content   string `json:"content"`
+		Snippet   string `json:"snippet"`
+	}
+	search := func(user testUser, query string) []hit {
+		t.Helper()
+		var hits page[hit]
+		app.expect(http.StatusOK, http.MethodGet, "/search/messages?"+query, user.Token, nil, &hits)
+		return hits.Items
+	}
+
+	if hits := search(alice, "q=release"); len(hits) != 5 {
+		t.Errorf("expected alice to find all five messages; got %+v", hits)
+	}
+	if hits := search(bob, "q=release"); len(hits) != 3 {
+		t.Errorf("expected bob to find the public and private messages only; got %+v", hits)
+	}
+	if hits := search(carol, "q=release"); len(hits) != 2 {
+		t.Errorf("expected carol to find the public messages only; got %+v", hits)
+	}
+
+	hits := search(bob, "q=party")
+	if len(hits) != 1 || hits[0].Snippet != "release <mark>party</mark> &lt;tonight&gt;" {
+		t.Errorf("expected an escaped, highlighted snippet; got %+v", hits)
+	}
+	if hits := search(bob, "q=release+has:link"); len(hits) != 1 || hits[0].ChannelID != general {
+		t.Errorf("expected has:link to keep the message with a link; got %+v", hits)
+	}
+	if hits := search(alice, "q=release&channel_id="+secret); len(hits) != 1 || hits[0].Kind != "channel" {
+		t.Errorf("expected the secret channel's message only; got %+v", hits)
+	}
+	if hits := search(alice, "q=releas*&after="+url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))); len(hits) != 0 {
+		t.Errorf("expected no messages sent in the future; got %+v", hits)
+	}
+
+	app.expect(http.StatusNotFound, http.MethodGet, "/search/messages?q=release&channel_id="+secret, bob.Token, nil, nil)
+	app.expect(http.StatusForbidden, http.MethodGet, "/search/messages?q=release&channel_id="+staff, bob.Token, nil, nil)
+	app.expect(http.StatusUnprocessableEntity, http.MethodGet, "/search/messages?q=+", bob.Token, nil, nil)
+	app.expect(http.StatusBadRequest, http.MethodGet, "/search/messages?q=release&after=yesterday", bob.Token, nil, nil)
+}
+
+func TestAdminRoutes(t *testing.T) {
+	app := newTestApp(t)
+	admin := app.signupAdmin("admin")
+	bob := app.signup("bob")
+	carol := app.signup("carol")
+	channel := app.createChannel(bob, "general", "public")
+
+	app.expect(http.StatusForbidden, http.MethodGet, "/admin/users", bob.Token, nil, nil)
+	app.expect(http.StatusBadRequest, http.MethodGet, "/admin/users?deleted=sometimes", admin.Token, nil, nil)
+
+	app.expect(http.StatusNoContent, http.MethodDelete, "/users/"+carol.ID, carol.Token, nil, nil, "If-Match", "*")
+	var users page[userBody]
+	app.expect(http.StatusOK, http.MethodGet, "/admin/users", admin.Token, nil, &users)
+	if len(users.Items) != 1 || users.Items[0].ID != carol.ID {
+		t.Fatalf("expected carol to be the only deleted user; got %+v", users.Items)
+	}
+	app.expect(http.StatusOK, http.MethodGet, "/admin/users?deleted=include", admin.Token, nil, &users)
+	if len(users.Items) != 3 {
+		t.Fatalf("expected all three users; got %+v", users.Items)
+	}
+	app.expect(http.StatusOK, http.MethodPost, "/admin/users/"+carol.ID+"/restore", admin.Token, nil, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/users/"+carol.ID, bob.Token, nil, nil)
+
+	app.expect(http.StatusNoContent, http.MethodDelete, "/channels/"+channel, bob.Token, nil, nil, "If-Match", "*")
+	var channels page[channelBody]
+	app.expect(http.StatusOK, http.MethodGet, "/admin/channels", admin.Token, nil, &channels)
+	if len(channels.Items) != 1 || channels.Items[0].ID != channel {
+		t.Fatalf("expected the deleted channel; got %+v", channels.Items)
+	}
+	app.expect(http.StatusOK, http.MethodPost, "/admin/channels/"+channel+"/restore", admin.Token, nil, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/channels/"+channel, bob.Token, nil, nil)
+
+	// A user cannot be purged while they still own channels.
+	app.expect(http.StatusNoContent, http.MethodDelete, "/users/"+bob.ID, bob.Token, nil, nil, "If-Match", "*")
+	app.expect(http.StatusConflict, http.MethodDelete, "/admin/users/"+bob.ID, admin.Token, nil, nil)
+	app.expect(http.StatusNoContent, http.MethodDelete, "/channels/"+channel, admin.Token, nil, nil, "If-Match", "*")
+	app.expect(http.StatusNoContent, http.MethodDelete, "/admin/channels/"+channel, admin.Token, nil, nil)
+	app.expect(http.StatusNoContent, http.MethodDelete, "/admin/users/"+bob.ID, admin.Token, nil, nil)
+	app.expect(http.StatusOK, http.MethodGet, "/admin/users?deleted=include", admin.Token, nil, &users)
+	if len(users.Items) != 2 {
+		t.Fatalf("expected bob to be purged; got %+v", users.Items)
+	}
+}
+
+func TestRealtimeRoutes(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	bob := app.signup("bob")
+	channel := app.createChannel(alice, "general", "public")
+
+	if resp := app.request(http.MethodGet, "/ws", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an unauthenticated websocket to be rejected; got %d", resp.StatusCode)
+	}
+	app.expect(http.StatusForbidden, http.MethodGet, "/channels/"+channel+"/events", bob.Token, nil, nil)
+
+	conn, _, err := websocket.DefaultDialer.Dial(app.wsURL("/ws?access_token="+alice.Token), nil)
+	if err != nil {
+		t.Fatalf("dialing websocket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteJSON(map[string]string{"type": "subscribe", "channel_id": channel}); err != nil {
+		t.Fatal(err)
+	}
+	var ack struct {
+		Type string `json:"type"`
+	}
+	if err := conn.ReadJSON(&ack); err != nil || ack.Type != "subscribed" {
+		t.Fatalf("expected the subscription to be acknowledged; got %+v, %v", ack, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, app.server.URL+"/channels/"+channel+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+alice.Token)
+	resp, err := app.server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream; got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+channel+"/messages", alice.Token,
+		map[string]string{"content": "hello"}, nil)
+
+	var event struct {
+		Type string      `json:"type"`
+		Data messageBody `json:"data"`
+	}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("reading websocket event: %v", err)
+	}
+	if event.Type != "channel_message.created" || event.Data.Content != "hello" {
+		t.Fatalf("unexpected websocket event %+v", event)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("event stream ended before the message event")
+			}
+			if strings.HasPrefix(line, "event: channel_message.created") {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the message event")
+		}
+	}
+}
+
+func TestServerDependencies(t *testing.T) {
+	app := newTestApp(t)
+	hub, reg := realtime.NewHub(), metrics.NewRegistry()
+	hub.Close()
+	srv := NewServer(app.srv.cfg, app.db, func(d *Dependencies) {
+		d.Hub = hub
+		d.Metrics = reg
+	})
+
+	if srv.Metrics() != reg {
+		t.Error("expected the server to use the given metrics registry")
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the closed hub to fail readiness; got %d", rec.Code)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.Install(tracing.NewProvider(sdktrace.WithSyncer(exporter), 1))
+
+	app := newTestApp(t)
+	alice := app.signup("alice")
+	channel := app.createChannel(alice, "general", "public")
+	exporter.Reset()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	app.expect(http.StatusCreated, http.MethodPost, "/channels/"+channel+"/messages", alice.Token,
+		map[string]string{"content": "hello"}, nil,
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if got := span.SpanContext.TraceID().String(); got != traceID {
+			t.Errorf("span %s: expected trace %s; got %s", span.Name, traceID, got)
+		}
+		byName[span.Name] = span
+	}
+
+	server, ok := byName["POST /channels/{id}/messages"]
+	if !ok {
+		t.Fatalf("expected a server span named after the route; got %v", spanNames(spans))
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the server span to continue the caller's span; got parent %s", server.Parent.SpanID())
+	}
+
+	// Each layer's span is a child of the one above it.
+	parents := map[string]string{
+		"ChannelMessageService.SendMessage": "POST /channels/{id}/messages",
+		"ChannelMessageRepository.Create":   "ChannelMessageService.SendMessage",
+		"gorm.create":                       "ChannelMessageRepository.Create",
+	}
+	for name, parent := range parents {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("expected a %s span; got %v", name, spanNames(spans))
+			continue
+		}
+		if span.Parent.SpanID() != byName[parent].SpanContext.SpanID() {
+			t.Errorf("expected %s to be a child of %s", name, parent)
+		}
+	}
+
+	var query string
+	for _, attr := range byName["gorm.create"].Attributes {
+		if attr.Key == "db.query.text" {
+			query = attr.Value.AsString()
+		}
+	}
+	if !strings.Contains(query, "INSERT INTO `channel_messages`") || strings.Contains(query, "hello") {
+		t.Errorf("expected the query text without its values; got %q", query)
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
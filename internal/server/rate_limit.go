@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+	"github.com/ruslanguns/go-chat/internal/handler"
+	"github.com/ruslanguns/go-chat/internal/ratelimit"
+)
+
+// Rate limit policies, named after their settings in config.RateLimitConfig.
+const (
+	policyAPI      = "api"
+	policySignup   = "signup"
+	policyLogin    = "login"
+	policyMessages = "messages"
+)
+
+// rateLimit returns the middleware enforcing the named policy, keyed by key.
+// It lets every request through when rate limiting is disabled.
+func (s *Server) rateLimit(policy string, key handler.RateLimitKey) func(http.Handler) http.Handler {
+	cfg := s.cfg.RateLimit
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	rule := map[string]config.RateLimitRule{
+		policyAPI:      cfg.API,
+		policySignup:   cfg.Signup,
+		policyLogin:    cfg.Login,
+		policyMessages: cfg.Messages,
+	}[policy]
+	return handler.RateLimit(s.rateLimits, handler.RateLimitPolicy{
+		Name:  policy,
+		Limit: ratelimit.Limit{Burst: rule.Requests, Per: rule.Per},
+		Key:   key,
+	})
+}
@@ -2,39 +2,121 @@ package server
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ruslanguns/go-chat/internal/handler"
+	"github.com/ruslanguns/go-chat/internal/health"
+	"github.com/ruslanguns/go-chat/internal/logging"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/tracing"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(logging.RequestID)
+	r.Use(tracing.HTTPMiddleware)
+	r.Use(logging.Middleware(s.logger))
+	r.Use(metrics.HTTPMiddleware(s.metrics))
 
 	r.Get("/", s.HelloWorldHandler)
 	r.Get("/health", s.healthHandler)
+	r.Get("/livez", s.probeHandler(s.liveness))
+	r.Get("/readyz", s.probeHandler(s.readiness))
+	r.Handle("/metrics", s.metrics.Handler())
+	// Operational endpoints above are exempt from rate limiting, so that
+	// probes and scrapes keep working for a client that is being limited.
+	r.Group(func(r chi.Router) {
+		r.Use(s.rateLimit(policyAPI, handler.ByIP))
 
-	// User routes
-	r.Route("/users", func(r chi.Router) {
-		r.Post("/", s.userHandler.Create)
-		r.Get("/", s.userHandler.List)
-		r.Get("/{id}", s.userHandler.Get)
-		r.Put("/{id}", s.userHandler.Update)
-		r.Delete("/{id}", s.userHandler.Delete)
-	})
+		r.With(s.authHandler.Authenticate).Get("/ws", s.webSocketHandler.Connect)
+
+		// Auth routes
+		r.Route("/auth", func(r chi.Router) {
+			r.With(s.rateLimit(policyLogin, handler.ByIP)).Post("/login", s.authHandler.Login)
+			r.Post("/refresh", s.authHandler.Refresh)
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.authHandler.Authenticate)
+				r.Post("/logout", s.authHandler.Logout)
+				r.Get("/me", s.authHandler.Me)
+			})
+		})
+
+		// User routes
+		r.Route("/users", func(r chi.Router) {
+			r.With(s.rateLimit(policySignup, handler.ByIP)).Post("/", s.userHandler.Create)
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.authHandler.Authenticate)
+				r.Get("/", s.userHandler.List)
+				r.Get("/{id}", s.userHandler.Get)
+				r.Put("/{id}", s.userHandler.Update)
+				r.Patch("/{id}", s.userHandler.Patch)
+				r.Delete("/{id}", s.userHandler.Delete)
+
+				// Private message routes
+				r.Get("/{id}/conversations", s.privateMessageHandler.ListConversations)
+				r.Get("/{id}/conversations/{counterpartId}/messages", s.privateMessageHandler.ListMessages)
+				r.With(s.rateLimit(policyMessages, handler.ByUser)).Post("/{id}/conversations/{counterpartId}/messages", s.privateMessageHandler.Send)
+				r.Post("/{id}/conversations/{counterpartId}/read", s.privateMessageHandler.MarkRead)
+			})
+		})
+
+		// Channel routes
+		r.Route("/channels", func(r chi.Router) {
+			r.Use(s.authHandler.Authenticate)
+			r.Post("/", s.channelHandler.Create)
+			r.Get("/", s.channelHandler.List)
+			r.Get("/{id}", s.channelHandler.Get)
+			r.Put("/{id}", s.channelHandler.Update)
+			r.Patch("/{id}", s.channelHandler.Patch)
+			r.Delete("/{id}", s.channelHandler.Delete)
+			r.Post("/{id}/users", s.channelHandler.AddUser)
+			r.Delete("/{id}/users/{userId}", s.channelHandler.RemoveUser)
+			r.Put("/{id}/users/{userId}/role", s.channelHandler.ChangeRole)
+			r.Get("/{id}/users", s.channelHandler.ListUsers)
+			r.Post("/{id}/join", s.channelHandler.Join)
+			r.Get("/{id}/events", s.channelEventHandler.Stream)
+
+			// Channel message routes
+			r.With(s.rateLimit(policyMessages, handler.ByUser)).Post("/{id}/messages", s.channelMessageHandler.Create)
+			r.Get("/{id}/messages", s.channelMessageHandler.List)
+			r.Get("/{id}/messages/{msgId}", s.channelMessageHandler.Get)
+			r.Put("/{id}/messages/{msgId}", s.channelMessageHandler.Update)
+			r.Delete("/{id}/messages/{msgId}", s.channelMessageHandler.Delete)
+
+			// Channel invite routes
+			r.Post("/{id}/invites", s.channelInviteHandler.Create)
+			r.Get("/{id}/invites", s.channelInviteHandler.List)
+			r.Delete("/{id}/invites/{inviteId}", s.channelInviteHandler.Revoke)
+		})
+
+		// Invite routes
+		r.Route("/invites", func(r chi.Router) {
+			r.Use(s.authHandler.Authenticate)
+			r.Get("/", s.channelInviteHandler.ListPending)
+			r.Post("/{code}/accept", s.channelInviteHandler.Accept)
+			r.Post("/{code}/decline", s.channelInviteHandler.Decline)
+		})
+
+		// Search routes
+		r.Route("/search", func(r chi.Router) {
+			r.Use(s.authHandler.Authenticate)
+			r.Get("/messages", s.searchHandler.SearchMessages)
+		})
+
+		// Admin routes
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(s.authHandler.Authenticate)
+			r.Get("/users", s.adminHandler.ListUsers)
+			r.Post("/users/{id}/restore", s.adminHandler.RestoreUser)
+			r.Delete("/users/{id}", s.adminHandler.PurgeUser)
+			r.Get("/channels", s.adminHandler.ListChannels)
+			r.Post("/channels/{id}/restore", s.adminHandler.RestoreChannel)
+			r.Delete("/channels/{id}", s.adminHandler.PurgeChannel)
+		})
 
-	// Channel routes
-	r.Route("/channels", func(r chi.Router) {
-		r.Post("/", s.channelHandler.Create)
-		r.Get("/", s.channelHandler.List)
-		r.Get("/{id}", s.channelHandler.Get)
-		r.Put("/{id}", s.channelHandler.Update)
-		r.Delete("/{id}", s.channelHandler.Delete)
-		r.Post("/{id}/users", s.channelHandler.AddUser)
-		r.Delete("/{id}/users/{userId}", s.channelHandler.RemoveUser)
-		r.Get("/{id}/users", s.channelHandler.ListUsers)
 	})
 
 	return r
@@ -46,13 +128,41 @@ func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 
 	jsonResp, err := json.Marshal(resp)
 	if err != nil {
-		log.Fatalf("error handling JSON marshal. Err: %v", err)
+		logging.FromContext(r.Context()).Error("encoding response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	_, _ = w.Write(jsonResp)
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, _ := json.Marshal(s.db.Health())
+	if s.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		jsonResp, _ := json.Marshal(map[string]string{"status": "draining"})
+		_, _ = w.Write(jsonResp)
+		return
+	}
+
+	stats := s.db.Health()
+	if stats["status"] != "up" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	jsonResp, _ := json.Marshal(stats)
 	_, _ = w.Write(jsonResp)
 }
+
+// probeHandler serves the report of a health check registry, with status 503
+// when any check fails.
+func (s *Server) probeHandler(registry health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := registry.Run(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != health.StatusUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -1,61 +1,236 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"strconv"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"github.com/ruslanguns/go-chat/internal/config"
 	"github.com/ruslanguns/go-chat/internal/database"
 	"github.com/ruslanguns/go-chat/internal/handler"
-	"github.com/ruslanguns/go-chat/internal/repository"
-	"github.com/ruslanguns/go-chat/internal/service"
+	"github.com/ruslanguns/go-chat/internal/health"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/ratelimit"
+	"github.com/ruslanguns/go-chat/internal/realtime"
 )
 
 type Server struct {
-	port int
+	cfg    *config.Config
+	logger *slog.Logger
 
-	db database.Service
+	db         database.Service
+	httpServer *http.Server
+	hub        *realtime.Hub
+	stream     *realtime.Stream
 
-	userHandler    *handler.UserHandler
-	channelHandler *handler.ChannelHandler
-}
+	draining  atomic.Bool
+	liveness  health.Registry
+	readiness health.Registry
+	metrics   metrics.Registry
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	db := database.New()
+	rateLimits ratelimit.Store
 
-	// Run Migrations
-	err := db.Migrate()
-	if err != nil {
-		panic(fmt.Sprintf("failed to run migrations %v", err))
-	}
-
-	gormDB := db.GetDB()
-	userRepo := repository.NewUserRepository(gormDB)
-	channelRepo := repository.NewChannelRepository(gormDB)
+	authHandler           *handler.AuthHandler
+	userHandler           *handler.UserHandler
+	channelHandler        *handler.ChannelHandler
+	channelMessageHandler *handler.ChannelMessageHandler
+	privateMessageHandler *handler.PrivateMessageHandler
+	webSocketHandler      *handler.WebSocketHandler
+	channelEventHandler   *handler.ChannelEventHandler
+	channelInviteHandler  *handler.ChannelInviteHandler
+	adminHandler          *handler.AdminHandler
+	searchHandler         *handler.SearchHandler
+}
 
-	userService := service.NewUserService(userRepo)
-	channelService := service.NewChannelService(channelRepo, userRepo)
+// NewServer builds the HTTP stack on top of db, which must already be
+// migrated. The server does not take ownership of db; the caller closes it
+// once the server has shut down. It logs through the default slog logger.
+// Options substitute the dependencies it would otherwise build itself.
+func NewServer(cfg *config.Config, db database.Service, opts ...Option) *Server {
+	var deps Dependencies
+	for _, opt := range opts {
+		opt(&deps)
+	}
+	deps = deps.withDefaults(cfg, db)
 
 	newServer := &Server{
-		port:           port,
-		db:             db,
-		userHandler:    handler.NewUserHandler(userService),
-		channelHandler: handler.NewChannelHandler(channelService),
+		cfg:                   cfg,
+		logger:                slog.Default(),
+		db:                    db,
+		hub:                   deps.Hub,
+		stream:                deps.Stream,
+		authHandler:           handler.NewAuthHandler(deps.AuthService),
+		userHandler:           handler.NewUserHandler(deps.UserService),
+		channelHandler:        handler.NewChannelHandler(deps.ChannelService),
+		channelMessageHandler: handler.NewChannelMessageHandler(deps.ChannelMessageService),
+		privateMessageHandler: handler.NewPrivateMessageHandler(deps.PrivateMessageService),
+		webSocketHandler:      handler.NewWebSocketHandler(deps.Hub, deps.ChannelService),
+		channelEventHandler:   handler.NewChannelEventHandler(deps.Stream, deps.ChannelService),
+		channelInviteHandler:  handler.NewChannelInviteHandler(deps.ChannelInviteService),
+		adminHandler:          handler.NewAdminHandler(deps.AdminService),
+		searchHandler:         handler.NewSearchHandler(deps.SearchService),
+		liveness:              health.NewRegistry(health.DefaultTimeout),
+		readiness:             health.NewRegistry(health.DefaultTimeout),
+		metrics:               deps.Metrics,
+		rateLimits:            deps.RateLimits,
 	}
+	newServer.registerHealthChecks()
+	newServer.registerMetrics()
 
 	// Declare Server config
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", newServer.port),
+	newServer.httpServer = &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler:      newServer.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	return newServer
+}
+
+// Handler returns the server's router, for serving it in tests.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Liveness returns the checks behind /livez. A failing liveness check means
+// the process should be restarted.
+func (s *Server) Liveness() health.Registry {
+	return s.liveness
+}
+
+// Readiness returns the checks behind /readyz. A failing readiness check means
+// the server should not be sent traffic for now.
+func (s *Server) Readiness() health.Registry {
+	return s.readiness
+}
+
+// registerHealthChecks registers the checks of the server's own dependencies.
+// Liveness has none: the process answering at all is proof enough of life.
+func (s *Server) registerHealthChecks() {
+	s.readiness.Register("server", health.CheckerFunc(func(ctx context.Context) error {
+		if s.draining.Load() {
+			return errors.New("shutting down")
+		}
+		return nil
+	}))
+	s.readiness.Register("database", health.CheckerFunc(s.db.Ping))
+	s.readiness.Register("migrations", health.CheckerFunc(func(ctx context.Context) error {
+		migrator, err := s.db.Migrator()
+		if err != nil {
+			return err
+		}
+		pending, err := database.Pending(migrator)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, starting with %s", len(pending), pending[0])
+		}
+		return nil
+	}))
+	s.readiness.Register("realtime_hub", s.hub)
+	s.readiness.Register("event_stream", s.stream)
+}
+
+// Metrics returns the registry served at /metrics.
+func (s *Server) Metrics() metrics.Registry {
+	return s.metrics
+}
+
+// registerMetrics registers gauges read from the database connection pool and
+// the realtime transports at scrape time.
+func (s *Server) registerMetrics() {
+	dbStat := func(stat func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return stat(s.db.Stats()) }
+	}
+	s.metrics.GaugeFunc("db_open_connections", "Open database connections, in use or idle.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.OpenConnections) }))
+	s.metrics.GaugeFunc("db_in_use_connections", "Database connections currently in use.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.InUse) }))
+	s.metrics.GaugeFunc("db_idle_connections", "Idle database connections.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.Idle) }))
+	s.metrics.CounterFunc("db_wait_count_total", "Database connections waited for.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.WaitCount) }))
+	s.metrics.CounterFunc("db_wait_duration_seconds_total", "Time spent waiting for database connections.",
+		dbStat(func(st sql.DBStats) float64 { return st.WaitDuration.Seconds() }))
+	s.metrics.CounterFunc("db_max_idle_closed_total", "Database connections closed because of the idle connection limit.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.MaxIdleClosed) }))
+	s.metrics.CounterFunc("db_max_lifetime_closed_total", "Database connections closed because of their maximum lifetime.",
+		dbStat(func(st sql.DBStats) float64 { return float64(st.MaxLifetimeClosed) }))
+
+	s.metrics.GaugeFunc("websocket_connections", "Connected websocket clients.",
+		func() float64 { return float64(s.hub.ConnectionCount()) })
+	s.metrics.GaugeFunc("event_stream_subscribers", "Connected Server-Sent Events subscribers.",
+		func() float64 { return float64(s.stream.SubscriberCount()) })
+}
+
+// Run serves HTTP until ctx is cancelled and then shuts the server down,
+// giving in-flight work up to the configured shutdown timeout to finish.
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("shutting down", "timeout", s.cfg.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
 	}
+	s.logger.Info("shutdown complete")
+	return nil
+}
+
+// Shutdown stops the server gracefully. It reports unhealthy for the
+// configured shutdown delay while still serving, then stops accepting
+// connections, disconnects realtime clients and waits for in-flight
+// requests. Work still running when ctx is done is cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
 
-	return server
+	select {
+	case <-time.After(s.cfg.Server.ShutdownDelay):
+	case <-ctx.Done():
+	}
+
+	// Event streams last as long as their clients stay connected, so end them
+	// rather than waiting for them to finish.
+	s.stream.Close()
+	hubErr := s.hub.Shutdown(ctx)
+	httpErr := s.httpServer.Shutdown(ctx)
+	if errors.Is(httpErr, context.DeadlineExceeded) {
+		s.httpServer.Close()
+	}
+
+	return errors.Join(httpErr, hubErr)
+}
+
+// authSecret returns the key used to sign access tokens. Without a configured
+// secret a random key is generated, so tokens do not survive a restart.
+func authSecret(cfg config.AuthConfig) []byte {
+	if cfg.Secret != "" {
+		return []byte(cfg.Secret)
+	}
+
+	slog.Warn("AUTH_SECRET is not set; using a random key, issued tokens will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate auth secret %v", err))
+	}
+	return secret
 }
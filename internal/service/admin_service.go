@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+// AdminService manages soft-deleted users and channels. Every method is
+// restricted to platform administrators.
+type AdminService interface {
+	ListUsers(ctx context.Context, actor *model.User, scope repository.DeletedScope, page pagination.Page) (*pagination.Result[*model.User], error)
+	RestoreUser(ctx context.Context, actor *model.User, id domain.EntityID) (*model.User, error)
+	PurgeUser(ctx context.Context, actor *model.User, id domain.EntityID) error
+	ListChannels(ctx context.Context, actor *model.User, scope repository.DeletedScope, page pagination.Page) (*pagination.Result[*model.Channel], error)
+	RestoreChannel(ctx context.Context, actor *model.User, id domain.EntityID) (*model.Channel, error)
+	PurgeChannel(ctx context.Context, actor *model.User, id domain.EntityID) error
+}
+
+type adminService struct {
+	userRepo    repository.UserRepository
+	channelRepo repository.ChannelRepository
+	authorizer  Authorizer
+}
+
+func NewAdminService(userRepo repository.UserRepository, channelRepo repository.ChannelRepository, authorizer Authorizer) AdminService {
+	return &adminService{
+		userRepo:    userRepo,
+		channelRepo: channelRepo,
+		authorizer:  authorizer,
+	}
+}
+
+func (s *adminService) ListUsers(ctx context.Context, actor *model.User, scope repository.DeletedScope, page pagination.Page) (*pagination.Result[*model.User], error) {
+	ctx, span := tracer.Start(ctx, "AdminService.ListUsers")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return nil, err
+	}
+	return s.userRepo.ListWithDeleted(ctx, scope, page)
+}
+
+func (s *adminService) RestoreUser(ctx context.Context, actor *model.User, id domain.EntityID) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.RestoreUser")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(ctx, id)
+}
+
+func (s *adminService) PurgeUser(ctx context.Context, actor *model.User, id domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "AdminService.PurgeUser")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return err
+	}
+	return s.userRepo.Purge(ctx, id)
+}
+
+func (s *adminService) ListChannels(ctx context.Context, actor *model.User, scope repository.DeletedScope, page pagination.Page) (*pagination.Result[*model.Channel], error) {
+	ctx, span := tracer.Start(ctx, "AdminService.ListChannels")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return nil, err
+	}
+	return s.channelRepo.ListWithDeleted(ctx, scope, page)
+}
+
+func (s *adminService) RestoreChannel(ctx context.Context, actor *model.User, id domain.EntityID) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.RestoreChannel")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return nil, err
+	}
+	if err := s.channelRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.channelRepo.GetByID(ctx, id)
+}
+
+func (s *adminService) PurgeChannel(ctx context.Context, actor *model.User, id domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "AdminService.PurgeChannel")
+	defer span.End()
+
+	if err := s.authorizer.CanAdminister(actor); err != nil {
+		return err
+	}
+	return s.channelRepo.Purge(ctx, id)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/auth"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+// refreshTTL is how long a session can be kept alive without logging in again.
+const refreshTTL = 30 * 24 * time.Hour
+
+type AuthService interface {
+	Login(ctx context.Context, login, password string) (*auth.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*auth.Tokens, error)
+	Logout(ctx context.Context, sessionID domain.EntityID) error
+	Authenticate(ctx context.Context, accessToken string) (*model.User, domain.EntityID, error)
+}
+
+type authService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	issuer      *auth.TokenIssuer
+}
+
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, issuer *auth.TokenIssuer) AuthService {
+	return &authService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		issuer:      issuer,
+	}
+}
+
+func (s *authService) Login(ctx context.Context, login, password string) (*auth.Tokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	login = strings.TrimSpace(login)
+
+	var user *model.User
+	var err error
+	if strings.Contains(login, "@") {
+		user, err = s.userRepo.GetByEmail(ctx, model.NormalizeEmail(login))
+	} else {
+		user, err = s.userRepo.GetByUsername(ctx, model.NormalizeUsername(login))
+	}
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			model.CheckMissingUserPassword(password)
+			return nil, errors.NewAppError(errors.ErrUnauthorized, "Invalid username or password")
+		}
+		return nil, err
+	}
+
+	if !user.CheckPassword(password) {
+		return nil, errors.NewAppError(errors.ErrUnauthorized, "Invalid username or password")
+	}
+
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to create session", err)
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: auth.HashRefreshToken(refreshToken),
+		ExpiresAt:        now.Add(refreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(session, refreshToken, now)
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*auth.Tokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Refresh")
+	defer span.End()
+
+	previousHash := auth.HashRefreshToken(refreshToken)
+	session, err := s.sessionRepo.GetByRefreshTokenHash(ctx, previousHash)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, errors.NewAppError(errors.ErrUnauthorized, "Invalid refresh token")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !session.IsActive(now) {
+		return nil, errors.NewAppError(errors.ErrUnauthorized, "Session has expired")
+	}
+
+	// Rotate the refresh token so a leaked one can only be used once, even by
+	// concurrent requests.
+	newRefreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to refresh session", err)
+	}
+	session.RefreshTokenHash = auth.HashRefreshToken(newRefreshToken)
+	session.ExpiresAt = now.Add(refreshTTL)
+	rotated, err := s.sessionRepo.Rotate(ctx, session, previousHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, errors.NewAppError(errors.ErrUnauthorized, "Invalid refresh token")
+	}
+
+	return s.issueTokens(session, newRefreshToken, now)
+}
+
+func (s *authService) Logout(ctx context.Context, sessionID domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.Revoke(time.Now())
+	return s.sessionRepo.Update(ctx, session)
+}
+
+func (s *authService) Authenticate(ctx context.Context, accessToken string) (*model.User, domain.EntityID, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	claims, err := s.issuer.Parse(accessToken)
+	if err != nil {
+		return nil, domain.EntityID{}, errors.NewAppError(errors.ErrUnauthorized, "Invalid access token")
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+	if err != nil || !session.IsActive(time.Now()) || session.UserID != claims.UserID {
+		return nil, domain.EntityID{}, errors.NewAppError(errors.ErrUnauthorized, "Session is no longer valid")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, domain.EntityID{}, errors.NewAppError(errors.ErrUnauthorized, "Session is no longer valid")
+	}
+
+	return user, session.ID, nil
+}
+
+func (s *authService) issueTokens(session *model.Session, refreshToken string, now time.Time) (*auth.Tokens, error) {
+	accessToken, err := s.issuer.Issue(session.UserID, session.ID, now)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to issue access token", err)
+	}
+
+	return &auth.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.issuer.AccessTTL().Seconds()),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+// Authorizer decides whether an actor may perform an action. Each method
+// returns nil when the action is allowed and an ErrForbidden AppError
+// otherwise. Platform administrators are allowed everything except reading
+// other users' conversations.
+//
+// Channel checks take the actor's membership of the channel, which is nil
+// when the actor is not a member.
+type Authorizer interface {
+	CanModifyUser(actor *model.User, userID domain.EntityID) error
+	CanViewChannel(actor *model.User, channel *model.Channel, member *model.ChannelMember) error
+	CanReadChannelMessages(actor *model.User, channel *model.Channel, member *model.ChannelMember) error
+	CanJoinChannel(actor *model.User, channel *model.Channel) error
+	CanAddMember(actor *model.User, member *model.ChannelMember) error
+	CanManageInvites(actor *model.User, channel *model.Channel, member *model.ChannelMember) error
+	CanUpdateChannel(actor *model.User, member *model.ChannelMember) error
+	CanDeleteChannel(actor *model.User, member *model.ChannelMember) error
+	CanRemoveMember(actor *model.User, member, target *model.ChannelMember) error
+	CanChangeRole(actor *model.User, member, target *model.ChannelMember, role model.ChannelRole) error
+	CanEditChannelMessage(actor *model.User, member *model.ChannelMember, message *model.ChannelMessage) error
+	CanDeleteChannelMessage(actor *model.User, member *model.ChannelMember, message *model.ChannelMessage) error
+	CanAccessConversations(actor *model.User, userID domain.EntityID) error
+	CanAdminister(actor *model.User) error
+}
+
+type authorizer struct{}
+
+func NewAuthorizer() Authorizer {
+	return &authorizer{}
+}
+
+func (a *authorizer) CanModifyUser(actor *model.User, userID domain.EntityID) error {
+	if actor.IsAdmin || actor.ID == userID {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "You can only modify your own account")
+}
+
+// CanViewChannel hides private channels from non-members by reporting them as
+// not found rather than forbidden.
+func (a *authorizer) CanViewChannel(actor *model.User, channel *model.Channel, member *model.ChannelMember) error {
+	if channel.Visibility != model.VisibilityPrivate || actor.IsAdmin || member != nil {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrNotFound, "Channel not found")
+}
+
+// CanReadChannelMessages lets anyone read the messages of public channels,
+// and only members those of invite-only and private channels.
+func (a *authorizer) CanReadChannelMessages(actor *model.User, channel *model.Channel, member *model.ChannelMember) error {
+	if channel.Visibility == model.VisibilityPublic || actor.IsAdmin || member != nil {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only members can read this channel's messages")
+}
+
+func (a *authorizer) CanJoinChannel(actor *model.User, channel *model.Channel) error {
+	if channel.Visibility == model.VisibilityPublic {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "This channel can only be joined with an invitation")
+}
+
+func (a *authorizer) CanAddMember(actor *model.User, member *model.ChannelMember) error {
+	if actor.IsAdmin || hasRole(member, model.RoleModerator) {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only moderators can add members")
+}
+
+func (a *authorizer) CanManageInvites(actor *model.User, channel *model.Channel, member *model.ChannelMember) error {
+	if actor.IsAdmin || hasRole(member, model.RoleModerator) {
+		return nil
+	}
+	if channel.Visibility == model.VisibilityPublic && member != nil {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only moderators can manage invites for this channel")
+}
+
+func (a *authorizer) CanUpdateChannel(actor *model.User, member *model.ChannelMember) error {
+	if actor.IsAdmin || hasRole(member, model.RoleAdmin) {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only channel admins can modify this channel")
+}
+
+func (a *authorizer) CanDeleteChannel(actor *model.User, member *model.ChannelMember) error {
+	if actor.IsAdmin || hasRole(member, model.RoleOwner) {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only the channel owner can delete this channel")
+}
+
+func (a *authorizer) CanRemoveMember(actor *model.User, member, target *model.ChannelMember) error {
+	if actor.ID == target.UserID {
+		if target.Role == model.RoleOwner {
+			return errors.NewAppError(errors.ErrForbidden, "The channel owner cannot leave the channel")
+		}
+		return nil
+	}
+	if actor.IsAdmin {
+		return nil
+	}
+	if hasRole(member, model.RoleModerator) && member.Role.Outranks(target.Role) {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only moderators can remove members of a lower role")
+}
+
+func (a *authorizer) CanChangeRole(actor *model.User, member, target *model.ChannelMember, role model.ChannelRole) error {
+	if actor.IsAdmin {
+		return nil
+	}
+	if !hasRole(member, model.RoleAdmin) {
+		return errors.NewAppError(errors.ErrForbidden, "Only channel admins can change roles")
+	}
+	if !member.Role.Outranks(target.Role) || !member.Role.Outranks(role) {
+		return errors.NewAppError(errors.ErrForbidden, "You can only assign roles below your own")
+	}
+	return nil
+}
+
+// CanEditChannelMessage lets senders edit their own messages for as long as
+// they remain members of the channel.
+func (a *authorizer) CanEditChannelMessage(actor *model.User, member *model.ChannelMember, message *model.ChannelMessage) error {
+	if actor.ID != message.SenderID {
+		return errors.NewAppError(errors.ErrForbidden, "You can only edit your own messages")
+	}
+	if member == nil {
+		return errors.NewAppError(errors.ErrForbidden, "Sender is not a member of the channel")
+	}
+	return nil
+}
+
+func (a *authorizer) CanDeleteChannelMessage(actor *model.User, member *model.ChannelMember, message *model.ChannelMessage) error {
+	if actor.IsAdmin || actor.ID == message.SenderID || hasRole(member, model.RoleModerator) {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only moderators can delete other members' messages")
+}
+
+func (a *authorizer) CanAccessConversations(actor *model.User, userID domain.EntityID) error {
+	if actor.ID == userID {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "You can only access your own conversations")
+}
+
+func (a *authorizer) CanAdminister(actor *model.User) error {
+	if actor.IsAdmin {
+		return nil
+	}
+	return errors.NewAppError(errors.ErrForbidden, "Only administrators can manage deleted records")
+}
+
+func hasRole(member *model.ChannelMember, role model.ChannelRole) bool {
+	return member != nil && member.Role.AtLeast(role)
+}
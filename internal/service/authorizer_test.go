@@ -0,0 +1,146 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+func newTestUser(isAdmin bool) *model.User {
+	return &model.User{BaseEntity: domain.BaseEntity{ID: domain.NewEntityID()}, IsAdmin: isAdmin}
+}
+
+func assertForbidden(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, errors.ErrForbidden) {
+		t.Errorf("expected forbidden error; got %v", err)
+	}
+}
+
+func TestAuthorizerUserRules(t *testing.T) {
+	a := NewAuthorizer()
+	self, other, admin := newTestUser(false), newTestUser(false), newTestUser(true)
+
+	if err := a.CanModifyUser(self, self.ID); err != nil {
+		t.Errorf("expected user to modify themselves; got %v", err)
+	}
+	if err := a.CanModifyUser(admin, self.ID); err != nil {
+		t.Errorf("expected admin to modify any user; got %v", err)
+	}
+	assertForbidden(t, a.CanModifyUser(other, self.ID))
+}
+
+func newTestMember(user *model.User, role model.ChannelRole) *model.ChannelMember {
+	return &model.ChannelMember{UserID: user.ID, Role: role}
+}
+
+func TestAuthorizerChannelRules(t *testing.T) {
+	a := NewAuthorizer()
+	owner, admin, moderator, member := newTestUser(false), newTestUser(false), newTestUser(false), newTestUser(false)
+	ownerM := newTestMember(owner, model.RoleOwner)
+	adminM := newTestMember(admin, model.RoleAdmin)
+	moderatorM := newTestMember(moderator, model.RoleModerator)
+	memberM := newTestMember(member, model.RoleMember)
+
+	if err := a.CanUpdateChannel(admin, adminM); err != nil {
+		t.Errorf("expected channel admin to update channel; got %v", err)
+	}
+	assertForbidden(t, a.CanUpdateChannel(moderator, moderatorM))
+	assertForbidden(t, a.CanUpdateChannel(newTestUser(false), nil))
+
+	if err := a.CanDeleteChannel(owner, ownerM); err != nil {
+		t.Errorf("expected owner to delete channel; got %v", err)
+	}
+	if err := a.CanDeleteChannel(newTestUser(true), nil); err != nil {
+		t.Errorf("expected platform admin to delete channel; got %v", err)
+	}
+	assertForbidden(t, a.CanDeleteChannel(admin, adminM))
+
+	if err := a.CanRemoveMember(member, memberM, memberM); err != nil {
+		t.Errorf("expected member to leave channel; got %v", err)
+	}
+	if err := a.CanRemoveMember(moderator, moderatorM, memberM); err != nil {
+		t.Errorf("expected moderator to remove member; got %v", err)
+	}
+	assertForbidden(t, a.CanRemoveMember(moderator, moderatorM, adminM))
+	assertForbidden(t, a.CanRemoveMember(member, memberM, moderatorM))
+	assertForbidden(t, a.CanRemoveMember(owner, ownerM, ownerM))
+
+	if err := a.CanChangeRole(owner, ownerM, memberM, model.RoleAdmin); err != nil {
+		t.Errorf("expected owner to promote member to admin; got %v", err)
+	}
+	if err := a.CanChangeRole(admin, adminM, memberM, model.RoleModerator); err != nil {
+		t.Errorf("expected admin to promote member to moderator; got %v", err)
+	}
+	assertForbidden(t, a.CanChangeRole(admin, adminM, memberM, model.RoleAdmin))
+	assertForbidden(t, a.CanChangeRole(moderator, moderatorM, memberM, model.RoleModerator))
+
+	message := &model.ChannelMessage{SenderID: member.ID}
+	if err := a.CanEditChannelMessage(member, memberM, message); err != nil {
+		t.Errorf("expected sender to edit message; got %v", err)
+	}
+	assertForbidden(t, a.CanEditChannelMessage(member, nil, message))
+	assertForbidden(t, a.CanEditChannelMessage(moderator, moderatorM, message))
+	if err := a.CanDeleteChannelMessage(moderator, moderatorM, message); err != nil {
+		t.Errorf("expected moderator to delete message; got %v", err)
+	}
+	assertForbidden(t, a.CanDeleteChannelMessage(newTestUser(false), memberM, message))
+}
+
+func TestAuthorizerVisibilityRules(t *testing.T) {
+	a := NewAuthorizer()
+	outsider, member := newTestUser(false), newTestUser(false)
+	memberM := newTestMember(member, model.RoleMember)
+	public := &model.Channel{Visibility: model.VisibilityPublic}
+	inviteOnly := &model.Channel{Visibility: model.VisibilityInviteOnly}
+	private := &model.Channel{Visibility: model.VisibilityPrivate}
+
+	if err := a.CanViewChannel(outsider, inviteOnly, nil); err != nil {
+		t.Errorf("expected outsider to see invite-only channel; got %v", err)
+	}
+	if err := a.CanViewChannel(member, private, memberM); err != nil {
+		t.Errorf("expected member to see private channel; got %v", err)
+	}
+	if err := a.CanViewChannel(outsider, private, nil); err == nil {
+		t.Error("expected private channel to be hidden from outsider")
+	}
+
+	if err := a.CanReadChannelMessages(outsider, public, nil); err != nil {
+		t.Errorf("expected outsider to read public channel; got %v", err)
+	}
+	if err := a.CanReadChannelMessages(member, inviteOnly, memberM); err != nil {
+		t.Errorf("expected member to read invite-only channel; got %v", err)
+	}
+	assertForbidden(t, a.CanReadChannelMessages(outsider, inviteOnly, nil))
+
+	if err := a.CanJoinChannel(outsider, public); err != nil {
+		t.Errorf("expected outsider to join public channel; got %v", err)
+	}
+	assertForbidden(t, a.CanJoinChannel(outsider, inviteOnly))
+
+	if err := a.CanManageInvites(member, public, memberM); err != nil {
+		t.Errorf("expected member to invite to public channel; got %v", err)
+	}
+	assertForbidden(t, a.CanManageInvites(member, private, memberM))
+}
+
+func TestAuthorizerConversationRules(t *testing.T) {
+	a := NewAuthorizer()
+	self := newTestUser(false)
+
+	if err := a.CanAccessConversations(self, self.ID); err != nil {
+		t.Errorf("expected user to read own conversations; got %v", err)
+	}
+	assertForbidden(t, a.CanAccessConversations(newTestUser(true), self.ID))
+}
+
+func TestAuthorizerAdminRules(t *testing.T) {
+	a := NewAuthorizer()
+
+	if err := a.CanAdminister(newTestUser(true)); err != nil {
+		t.Errorf("expected admin to manage deleted records; got %v", err)
+	}
+	assertForbidden(t, a.CanAdminister(newTestUser(false)))
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+type ChannelInviteService interface {
+	CreateInvite(ctx context.Context, actor *model.User, channelID, inviteeID domain.EntityID, maxUses int, expiresIn time.Duration) (*model.ChannelInvite, error)
+	ListChannelInvites(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error)
+	ListPendingInvites(ctx context.Context, actor *model.User, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error)
+	RevokeInvite(ctx context.Context, actor *model.User, channelID, inviteID domain.EntityID) error
+	AcceptInvite(ctx context.Context, actor *model.User, code string) (*model.Channel, error)
+	DeclineInvite(ctx context.Context, actor *model.User, code string) error
+}
+
+type channelInviteService struct {
+	inviteRepo  repository.ChannelInviteRepository
+	channelRepo repository.ChannelRepository
+	userRepo    repository.UserRepository
+	transactor  repository.Transactor
+	publisher   EventPublisher
+	authorizer  Authorizer
+
+	membershipChanges metrics.Counter
+}
+
+func NewChannelInviteService(inviteRepo repository.ChannelInviteRepository, channelRepo repository.ChannelRepository, userRepo repository.UserRepository, transactor repository.Transactor, publisher EventPublisher, authorizer Authorizer, reg metrics.Registry) ChannelInviteService {
+	return &channelInviteService{
+		inviteRepo:        inviteRepo,
+		channelRepo:       channelRepo,
+		userRepo:          userRepo,
+		transactor:        transactor,
+		publisher:         publisher,
+		authorizer:        authorizer,
+		membershipChanges: membershipChanges(reg),
+	}
+}
+
+// CreateInvite creates an invite to the channel. When inviteeID is set the
+// invite is addressed to that user and can be used once; otherwise it is a
+// link invite usable maxUses times (0 for unlimited). A zero expiresIn
+// creates an invite that never expires.
+func (s *channelInviteService) CreateInvite(ctx context.Context, actor *model.User, channelID, inviteeID domain.EntityID, maxUses int, expiresIn time.Duration) (*model.ChannelInvite, error) {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.CreateInvite")
+	defer span.End()
+
+	if maxUses < 0 || expiresIn < 0 {
+		return nil, errors.NewAppError(errors.ErrInvalidInput, "Invalid invite data")
+	}
+
+	channel, err := s.manageableChannel(ctx, actor, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inviteeID.IsZero() {
+		_, err := s.userRepo.GetByID(ctx, inviteeID)
+		if err != nil {
+			return nil, err
+		}
+
+		isMember, err := s.channelRepo.IsMember(ctx, channelID, inviteeID)
+		if err != nil {
+			return nil, err
+		}
+		if isMember {
+			return nil, errors.NewAppError(errors.ErrAlreadyExists, "User is already a member of the channel")
+		}
+		maxUses = 1
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "Failed to create invite", err)
+	}
+
+	invite := &model.ChannelInvite{
+		ChannelID: channel.ID,
+		InviterID: actor.ID,
+		InviteeID: inviteeID,
+		Code:      code,
+		MaxUses:   maxUses,
+		Status:    model.InviteStatusPending,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	err = s.inviteRepo.Create(ctx, invite)
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (s *channelInviteService) ListChannelInvites(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error) {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.ListChannelInvites")
+	defer span.End()
+
+	_, err := s.manageableChannel(ctx, actor, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.ListByChannel(ctx, channelID, page)
+}
+
+func (s *channelInviteService) ListPendingInvites(ctx context.Context, actor *model.User, page pagination.Page) (*pagination.Result[*model.ChannelInvite], error) {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.ListPendingInvites")
+	defer span.End()
+
+	return s.inviteRepo.ListPendingForUser(ctx, actor.ID, page)
+}
+
+func (s *channelInviteService) RevokeInvite(ctx context.Context, actor *model.User, channelID, inviteID domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.RevokeInvite")
+	defer span.End()
+
+	invite, err := s.inviteRepo.GetByID(ctx, channelID, inviteID)
+	if err != nil {
+		return err
+	}
+
+	if invite.InviterID != actor.ID {
+		_, err := s.manageableChannel(ctx, actor, channelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.inviteRepo.UpdateStatus(ctx, invite.ID, model.InviteStatusRevoked)
+}
+
+func (s *channelInviteService) AcceptInvite(ctx context.Context, actor *model.User, code string) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.AcceptInvite")
+	defer span.End()
+
+	invite, err := s.inviteFor(ctx, actor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !invite.IsUsable(time.Now()) {
+		return nil, errors.NewAppError(errors.ErrForbidden, "Invite is no longer valid")
+	}
+
+	channel, err := s.channelRepo.GetByID(ctx, invite.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.channelRepo.IsMember(ctx, channel.ID, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, errors.NewAppError(errors.ErrAlreadyExists, "User is already a member of the channel")
+	}
+
+	// Spend the use and add the member together, so that a failed join
+	// leaves the invite as it was.
+	err = s.transactor.Transaction(ctx, func(ctx context.Context, tx repository.Tx) error {
+		consumed, err := tx.ChannelInvites().ConsumeUse(ctx, invite.ID)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return errors.NewAppError(errors.ErrForbidden, "Invite is no longer valid")
+		}
+
+		if err := tx.Channels().AddUser(ctx, channel.ID, actor.ID, model.RoleMember); err != nil {
+			return err
+		}
+
+		if invite.IsDirect() {
+			return tx.ChannelInvites().UpdateStatus(ctx, invite.ID, model.InviteStatusAccepted)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.membershipChanges.Inc(membershipJoined)
+	s.publisher.ChannelMemberAdded(channel.ID, actor.ID)
+
+	return channel, nil
+}
+
+func (s *channelInviteService) DeclineInvite(ctx context.Context, actor *model.User, code string) error {
+	ctx, span := tracer.Start(ctx, "ChannelInviteService.DeclineInvite")
+	defer span.End()
+
+	invite, err := s.inviteFor(ctx, actor, code)
+	if err != nil {
+		return err
+	}
+
+	if !invite.IsDirect() {
+		return errors.NewAppError(errors.ErrInvalidInput, "Only personal invites can be declined")
+	}
+	return s.inviteRepo.UpdateStatus(ctx, invite.ID, model.InviteStatusDeclined)
+}
+
+// inviteFor loads an invite by code, hiding invites addressed to someone else.
+func (s *channelInviteService) inviteFor(ctx context.Context, actor *model.User, code string) (*model.ChannelInvite, error) {
+	invite, err := s.inviteRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if invite.IsDirect() && invite.InviteeID != actor.ID {
+		return nil, errors.NewAppError(errors.ErrNotFound, "Invite not found")
+	}
+
+	return invite, nil
+}
+
+// manageableChannel loads a channel and checks that actor may manage its
+// invites.
+func (s *channelInviteService) manageableChannel(ctx context.Context, actor *model.User, channelID domain.EntityID) (*model.Channel, error) {
+	channel, err := visibleChannel(ctx, s.channelRepo, s.authorizer, actor, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizer.CanManageInvites(actor, channel, member); err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+func newInviteCode() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+type ChannelMessageService interface {
+	SendMessage(ctx context.Context, actor *model.User, channelID domain.EntityID, content string) (*model.ChannelMessage, error)
+	GetMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID) (*model.ChannelMessage, error)
+	UpdateMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID, version int64, content string) (*model.ChannelMessage, error)
+	DeleteMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID, version int64) error
+	ListMessages(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelMessage], error)
+}
+
+type channelMessageService struct {
+	messageRepo repository.ChannelMessageRepository
+	channelRepo repository.ChannelRepository
+	publisher   EventPublisher
+	authorizer  Authorizer
+
+	messagesSent metrics.Counter
+}
+
+func NewChannelMessageService(messageRepo repository.ChannelMessageRepository, channelRepo repository.ChannelRepository, publisher EventPublisher, authorizer Authorizer, reg metrics.Registry) ChannelMessageService {
+	return &channelMessageService{
+		messageRepo:  messageRepo,
+		channelRepo:  channelRepo,
+		publisher:    publisher,
+		authorizer:   authorizer,
+		messagesSent: messagesSent(reg),
+	}
+}
+
+func (s *channelMessageService) SendMessage(ctx context.Context, actor *model.User, channelID domain.EntityID, content string) (*model.ChannelMessage, error) {
+	ctx, span := tracer.Start(ctx, "ChannelMessageService.SendMessage")
+	defer span.End()
+
+	message, err := model.NewChannelMessage(channelID, actor.ID, content)
+	if err != nil {
+		return nil, validationError(err, "Invalid message data")
+	}
+
+	_, err = s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.channelRepo.IsMember(ctx, channelID, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewAppError(errors.ErrForbidden, "Sender is not a member of the channel")
+	}
+
+	err = s.messageRepo.Create(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.messagesSent.Inc(messageKindChannel)
+	s.publisher.ChannelMessageCreated(message)
+
+	return message, nil
+}
+
+func (s *channelMessageService) GetMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID) (*model.ChannelMessage, error) {
+	ctx, span := tracer.Start(ctx, "ChannelMessageService.GetMessage")
+	defer span.End()
+
+	if err := readableChannel(ctx, s.channelRepo, s.authorizer, actor, channelID); err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.GetByID(ctx, channelID, messageID)
+}
+
+func (s *channelMessageService) UpdateMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID, version int64, content string) (*model.ChannelMessage, error) {
+	ctx, span := tracer.Start(ctx, "ChannelMessageService.UpdateMessage")
+	defer span.End()
+
+	message, err := s.messageRepo.GetByID(ctx, channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizer.CanEditChannelMessage(actor, member, message); err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(&message.BaseEntity, version); err != nil {
+		return nil, err
+	}
+
+	if err := message.ChangeContent(content); err != nil {
+		return nil, validationError(err, "Invalid message data")
+	}
+
+	err = s.messageRepo.Update(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publisher.ChannelMessageUpdated(message)
+
+	return message, nil
+}
+
+func (s *channelMessageService) DeleteMessage(ctx context.Context, actor *model.User, channelID, messageID domain.EntityID, version int64) error {
+	ctx, span := tracer.Start(ctx, "ChannelMessageService.DeleteMessage")
+	defer span.End()
+
+	message, err := s.messageRepo.GetByID(ctx, channelID, messageID)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorizer.CanDeleteChannelMessage(actor, member, message); err != nil {
+		return err
+	}
+
+	if err := checkVersion(&message.BaseEntity, version); err != nil {
+		return err
+	}
+
+	err = s.messageRepo.Delete(ctx, channelID, messageID, message.Version)
+	if err != nil {
+		return err
+	}
+
+	s.publisher.ChannelMessageDeleted(channelID, messageID)
+
+	return nil
+}
+
+func (s *channelMessageService) ListMessages(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelMessage], error) {
+	ctx, span := tracer.Start(ctx, "ChannelMessageService.ListMessages")
+	defer span.End()
+
+	if err := readableChannel(ctx, s.channelRepo, s.authorizer, actor, channelID); err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.ListByChannel(ctx, channelID, page)
+}
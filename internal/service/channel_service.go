@@ -1,87 +1,399 @@
 package service
 
 import (
+	"context"
+
 	"github.com/ruslanguns/go-chat/internal/domain"
 	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"github.com/ruslanguns/go-chat/internal/repository"
 )
 
 type ChannelService interface {
-	CreateChannel(name, description string) (*model.Channel, error)
-	GetChannelByID(id domain.EntityID) (*model.Channel, error)
-	GetChannelByName(name string) (*model.Channel, error)
-	UpdateChannel(channel *model.Channel) error
-	DeleteChannel(id domain.EntityID) error
-	ListChannels(offset, limit int) ([]*model.Channel, error)
-	AddUserToChannel(channelID, userID domain.EntityID) error
-	RemoveUserFromChannel(channelID, userID domain.EntityID) error
-	GetChannelUsers(channelID domain.EntityID, offset, limit int) ([]*model.User, error)
+	CreateChannel(ctx context.Context, actor *model.User, name, description string, visibility model.ChannelVisibility) (*model.Channel, error)
+	GetChannelByID(ctx context.Context, actor *model.User, id domain.EntityID) (*model.Channel, error)
+	GetChannelByName(ctx context.Context, name string) (*model.Channel, error)
+	UpdateChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64, name, description string, visibility model.ChannelVisibility) (*model.Channel, error)
+	PatchChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64, patch ChannelPatch) (*model.Channel, error)
+	DeleteChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64) error
+	ListChannels(ctx context.Context, actor *model.User, page pagination.Page) (*pagination.Result[*model.Channel], error)
+	JoinChannel(ctx context.Context, actor *model.User, channelID domain.EntityID) error
+	AddUserToChannel(ctx context.Context, actor *model.User, channelID, userID domain.EntityID) error
+	RemoveUserFromChannel(ctx context.Context, actor *model.User, channelID, userID domain.EntityID) error
+	ChangeMemberRole(ctx context.Context, actor *model.User, channelID, userID domain.EntityID, role model.ChannelRole) error
+	GetChannelUsers(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelUser], error)
+	IsChannelMember(ctx context.Context, channelID, userID domain.EntityID) (bool, error)
+}
+
+// ChannelPatch lists the channel fields to change; nil fields are left
+// unchanged.
+type ChannelPatch struct {
+	Name        *string
+	Description *string
+	Visibility  *model.ChannelVisibility
 }
 
 type channelService struct {
 	channelRepo repository.ChannelRepository
 	userRepo    repository.UserRepository
+	transactor  repository.Transactor
+	publisher   EventPublisher
+	authorizer  Authorizer
+
+	channelsCreated   metrics.Counter
+	membershipChanges metrics.Counter
 }
 
-func NewChannelService(channelRepo repository.ChannelRepository, userRepo repository.UserRepository) ChannelService {
+func NewChannelService(channelRepo repository.ChannelRepository, userRepo repository.UserRepository, transactor repository.Transactor, publisher EventPublisher, authorizer Authorizer, reg metrics.Registry) ChannelService {
 	return &channelService{
-		channelRepo: channelRepo,
-		userRepo:    userRepo,
+		channelRepo:       channelRepo,
+		userRepo:          userRepo,
+		transactor:        transactor,
+		publisher:         publisher,
+		authorizer:        authorizer,
+		channelsCreated:   channelsCreated(reg),
+		membershipChanges: membershipChanges(reg),
 	}
 }
 
-func (s *channelService) CreateChannel(name, description string) (*model.Channel, error) {
-	channel := &model.Channel{
-		Name:        name,
-		Description: description,
+func (s *channelService) CreateChannel(ctx context.Context, actor *model.User, name, description string, visibility model.ChannelVisibility) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.CreateChannel")
+	defer span.End()
+
+	channel, err := model.NewChannel(actor.ID, name, description, visibility)
+	if err != nil {
+		return nil, validationError(err, "Invalid channel data")
 	}
 
-	err := s.channelRepo.Create(channel)
+	// Create the channel and its owner's membership together, so that a
+	// channel is never left without an owner.
+	err = s.transactor.Transaction(ctx, func(ctx context.Context, tx repository.Tx) error {
+		if err := tx.Channels().Create(ctx, channel); err != nil {
+			return err
+		}
+		return tx.Channels().AddUser(ctx, channel.ID, actor.ID, model.RoleOwner)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	s.channelsCreated.Inc()
+
 	return channel, nil
 }
 
-func (s *channelService) GetChannelByID(id domain.EntityID) (*model.Channel, error) {
-	return s.channelRepo.GetByID(id)
+func (s *channelService) GetChannelByID(ctx context.Context, actor *model.User, id domain.EntityID) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.GetChannelByID")
+	defer span.End()
+
+	return visibleChannel(ctx, s.channelRepo, s.authorizer, actor, id)
 }
 
-func (s *channelService) GetChannelByName(name string) (*model.Channel, error) {
-	return s.channelRepo.GetByName(name)
+func (s *channelService) GetChannelByName(ctx context.Context, name string) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.GetChannelByName")
+	defer span.End()
+
+	return s.channelRepo.GetByName(ctx, model.NormalizeChannelName(name))
 }
 
-func (s *channelService) UpdateChannel(channel *model.Channel) error {
-	return s.channelRepo.Update(channel)
+// UpdateChannel replaces every editable field of the channel, provided it is
+// still at the given version. An empty visibility resets it to public.
+func (s *channelService) UpdateChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64, name, description string, visibility model.ChannelVisibility) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.UpdateChannel")
+	defer span.End()
+
+	if visibility == "" {
+		visibility = model.VisibilityPublic
+	}
+	return s.PatchChannel(ctx, actor, id, version, ChannelPatch{Name: &name, Description: &description, Visibility: &visibility})
 }
 
-func (s *channelService) DeleteChannel(id domain.EntityID) error {
-	return s.channelRepo.Delete(id)
+// PatchChannel changes only the fields set in patch, provided the channel is
+// still at the given version.
+func (s *channelService) PatchChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64, patch ChannelPatch) (*model.Channel, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.PatchChannel")
+	defer span.End()
+
+	channel, err := s.channelRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, id, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizer.CanUpdateChannel(actor, member); err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(&channel.BaseEntity, version); err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if err := channel.Rename(*patch.Name); err != nil {
+			return nil, validationError(err, "Invalid channel data")
+		}
+	}
+	if patch.Description != nil {
+		if err := channel.ChangeDescription(*patch.Description); err != nil {
+			return nil, validationError(err, "Invalid channel data")
+		}
+	}
+	if patch.Visibility != nil {
+		if err := channel.ChangeVisibility(*patch.Visibility); err != nil {
+			return nil, validationError(err, "Invalid channel data")
+		}
+	}
+
+	if err := s.channelRepo.Update(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	return channel, nil
 }
 
-func (s *channelService) ListChannels(offset, limit int) ([]*model.Channel, error) {
-	return s.channelRepo.List(offset, limit)
+func (s *channelService) DeleteChannel(ctx context.Context, actor *model.User, id domain.EntityID, version int64) error {
+	ctx, span := tracer.Start(ctx, "ChannelService.DeleteChannel")
+	defer span.End()
+
+	channel, err := s.channelRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, id, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorizer.CanDeleteChannel(actor, member); err != nil {
+		return err
+	}
+
+	if err := checkVersion(&channel.BaseEntity, version); err != nil {
+		return err
+	}
+
+	if err := s.channelRepo.Delete(ctx, id, channel.Version); err != nil {
+		return err
+	}
+
+	s.publisher.ChannelDeleted(id)
+
+	return nil
 }
 
-func (s *channelService) AddUserToChannel(channelID, userID domain.EntityID) error {
-	_, err := s.channelRepo.GetByID(channelID)
+func (s *channelService) ListChannels(ctx context.Context, actor *model.User, page pagination.Page) (*pagination.Result[*model.Channel], error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.ListChannels")
+	defer span.End()
+
+	if actor.IsAdmin {
+		return s.channelRepo.List(ctx, page)
+	}
+	return s.channelRepo.ListVisibleTo(ctx, actor.ID, page)
+}
+
+func (s *channelService) JoinChannel(ctx context.Context, actor *model.User, channelID domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "ChannelService.JoinChannel")
+	defer span.End()
+
+	channel, err := visibleChannel(ctx, s.channelRepo, s.authorizer, actor, channelID)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.userRepo.GetByID(userID)
+	if err := s.authorizer.CanJoinChannel(actor, channel); err != nil {
+		return err
+	}
+
+	err = s.channelRepo.AddUser(ctx, channelID, actor.ID, model.RoleMember)
 	if err != nil {
 		return err
 	}
 
-	return s.channelRepo.AddUser(channelID, userID)
+	s.membershipChanges.Inc(membershipJoined)
+	s.publisher.ChannelMemberAdded(channelID, actor.ID)
+
+	return nil
 }
 
-func (s *channelService) RemoveUserFromChannel(channelID, userID domain.EntityID) error {
-	return s.channelRepo.RemoveUser(channelID, userID)
+func (s *channelService) AddUserToChannel(ctx context.Context, actor *model.User, channelID, userID domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "ChannelService.AddUserToChannel")
+	defer span.End()
+
+	_, err := visibleChannel(ctx, s.channelRepo, s.authorizer, actor, channelID)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorizer.CanAddMember(actor, member); err != nil {
+		return err
+	}
+
+	_, err = s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.channelRepo.AddUser(ctx, channelID, userID, model.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	s.membershipChanges.Inc(membershipAdded)
+	s.publisher.ChannelMemberAdded(channelID, userID)
+
+	return nil
 }
 
-func (s *channelService) GetChannelUsers(channelID domain.EntityID, offset, limit int) ([]*model.User, error) {
-	return s.channelRepo.GetUsers(channelID, offset, limit)
+func (s *channelService) RemoveUserFromChannel(ctx context.Context, actor *model.User, channelID, userID domain.EntityID) error {
+	ctx, span := tracer.Start(ctx, "ChannelService.RemoveUserFromChannel")
+	defer span.End()
+
+	_, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.channelRepo.GetMember(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorizer.CanRemoveMember(actor, member, target); err != nil {
+		return err
+	}
+
+	err = s.channelRepo.RemoveUser(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.membershipChanges.Inc(membershipRemoved)
+	s.publisher.ChannelMemberRemoved(channelID, userID)
+
+	return nil
+}
+
+func (s *channelService) ChangeMemberRole(ctx context.Context, actor *model.User, channelID, userID domain.EntityID, role model.ChannelRole) error {
+	ctx, span := tracer.Start(ctx, "ChannelService.ChangeMemberRole")
+	defer span.End()
+
+	if !role.IsValid() || role == model.RoleOwner {
+		return errors.NewAppError(errors.ErrInvalidInput, "Invalid role")
+	}
+
+	_, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.channelRepo.GetMember(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, s.channelRepo, channelID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorizer.CanChangeRole(actor, member, target, role); err != nil {
+		return err
+	}
+
+	err = s.channelRepo.UpdateMemberRole(ctx, channelID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	s.membershipChanges.Inc(membershipRoleChanged)
+	s.publisher.ChannelMemberRoleChanged(channelID, userID, role)
+
+	return nil
+}
+
+func (s *channelService) GetChannelUsers(ctx context.Context, actor *model.User, channelID domain.EntityID, page pagination.Page) (*pagination.Result[*model.ChannelUser], error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.GetChannelUsers")
+	defer span.End()
+
+	if err := readableChannel(ctx, s.channelRepo, s.authorizer, actor, channelID); err != nil {
+		return nil, err
+	}
+
+	return s.channelRepo.GetUsers(ctx, channelID, page)
+}
+
+func (s *channelService) IsChannelMember(ctx context.Context, channelID, userID domain.EntityID) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ChannelService.IsChannelMember")
+	defer span.End()
+
+	return s.channelRepo.IsMember(ctx, channelID, userID)
+}
+
+// memberOrNil returns the user's membership of the channel, or nil when the
+// user is not a member.
+func memberOrNil(ctx context.Context, channelRepo repository.ChannelRepository, channelID, userID domain.EntityID) (*model.ChannelMember, error) {
+	member, err := channelRepo.GetMember(ctx, channelID, userID)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// visibleChannel loads a channel, reporting it as not found when actor is not
+// allowed to see it.
+func visibleChannel(ctx context.Context, channelRepo repository.ChannelRepository, authorizer Authorizer, actor *model.User, channelID domain.EntityID) (*model.Channel, error) {
+	channel, err := channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := memberOrNil(ctx, channelRepo, channelID, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizer.CanViewChannel(actor, channel, member); err != nil {
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+// readableChannel checks that actor may read the messages of a channel,
+// reporting it as not found when actor is not allowed to see it.
+func readableChannel(ctx context.Context, channelRepo repository.ChannelRepository, authorizer Authorizer, actor *model.User, channelID domain.EntityID) error {
+	channel, err := channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	member, err := memberOrNil(ctx, channelRepo, channelID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizer.CanViewChannel(actor, channel, member); err != nil {
+		return err
+	}
+	return authorizer.CanReadChannelMessages(actor, channel, member)
 }
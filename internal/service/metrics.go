@@ -0,0 +1,34 @@
+package service
+
+import "github.com/ruslanguns/go-chat/internal/metrics"
+
+// Label values of the domain metrics.
+const (
+	messageKindChannel = "channel"
+	messageKindPrivate = "private"
+
+	membershipJoined      = "joined"
+	membershipAdded       = "added"
+	membershipRemoved     = "removed"
+	membershipRoleChanged = "role_changed"
+)
+
+// Domain metrics shared by several services. Registering them again returns
+// the same metric, so each service registers those it records.
+
+func messagesSent(reg metrics.Registry) metrics.Counter {
+	return reg.Counter("messages_sent_total",
+		"Messages sent, by kind: channel or private.",
+		"kind")
+}
+
+func channelsCreated(reg metrics.Registry) metrics.Counter {
+	return reg.Counter("channels_created_total",
+		"Channels created.")
+}
+
+func membershipChanges(reg metrics.Registry) metrics.Counter {
+	return reg.Counter("channel_membership_changes_total",
+		"Channel membership changes, by change: joined, added, removed or role_changed.",
+		"change")
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/metrics"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+type PrivateMessageService interface {
+	SendMessage(ctx context.Context, actor *model.User, senderID, receiverID domain.EntityID, content string) (*model.PrivateMessage, error)
+	ListConversations(ctx context.Context, actor *model.User, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Conversation], error)
+	GetConversation(ctx context.Context, actor *model.User, userID, counterpartID domain.EntityID, page pagination.Page) (*pagination.Result[*model.PrivateMessage], error)
+	MarkConversationRead(ctx context.Context, actor *model.User, userID, counterpartID domain.EntityID) (int64, error)
+}
+
+type privateMessageService struct {
+	messageRepo repository.PrivateMessageRepository
+	userRepo    repository.UserRepository
+	publisher   EventPublisher
+	authorizer  Authorizer
+
+	messagesSent metrics.Counter
+}
+
+func NewPrivateMessageService(messageRepo repository.PrivateMessageRepository, userRepo repository.UserRepository, publisher EventPublisher, authorizer Authorizer, reg metrics.Registry) PrivateMessageService {
+	return &privateMessageService{
+		messageRepo:  messageRepo,
+		userRepo:     userRepo,
+		publisher:    publisher,
+		authorizer:   authorizer,
+		messagesSent: messagesSent(reg),
+	}
+}
+
+func (s *privateMessageService) SendMessage(ctx context.Context, actor *model.User, senderID, receiverID domain.EntityID, content string) (*model.PrivateMessage, error) {
+	ctx, span := tracer.Start(ctx, "PrivateMessageService.SendMessage")
+	defer span.End()
+
+	if err := s.authorizer.CanAccessConversations(actor, senderID); err != nil {
+		return nil, err
+	}
+
+	message, err := model.NewPrivateMessage(senderID, receiverID, content)
+	if err != nil {
+		return nil, validationError(err, "Invalid message data")
+	}
+
+	_, err = s.userRepo.GetByID(ctx, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.userRepo.GetByID(ctx, receiverID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.messageRepo.Create(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.messagesSent.Inc(messageKindPrivate)
+	s.publisher.PrivateMessageCreated(message)
+
+	return message, nil
+}
+
+func (s *privateMessageService) ListConversations(ctx context.Context, actor *model.User, userID domain.EntityID, page pagination.Page) (*pagination.Result[*model.Conversation], error) {
+	ctx, span := tracer.Start(ctx, "PrivateMessageService.ListConversations")
+	defer span.End()
+
+	if err := s.authorizer.CanAccessConversations(actor, userID); err != nil {
+		return nil, err
+	}
+	if page.After != nil {
+		return nil, errors.NewAppError(errors.ErrInvalidInput, "Conversations are paged by offset, not cursor")
+	}
+
+	_, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.ListConversations(ctx, userID, page)
+}
+
+func (s *privateMessageService) GetConversation(ctx context.Context, actor *model.User, userID, counterpartID domain.EntityID, page pagination.Page) (*pagination.Result[*model.PrivateMessage], error) {
+	ctx, span := tracer.Start(ctx, "PrivateMessageService.GetConversation")
+	defer span.End()
+
+	if err := s.authorizer.CanAccessConversations(actor, userID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.ListConversation(ctx, userID, counterpartID, page)
+}
+
+func (s *privateMessageService) MarkConversationRead(ctx context.Context, actor *model.User, userID, counterpartID domain.EntityID) (int64, error) {
+	ctx, span := tracer.Start(ctx, "PrivateMessageService.MarkConversationRead")
+	defer span.End()
+
+	if err := s.authorizer.CanAccessConversations(actor, userID); err != nil {
+		return 0, err
+	}
+
+	_, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.messageRepo.MarkConversationRead(ctx, userID, counterpartID, time.Now())
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+)
+
+// EventPublisher is notified once a change has been persisted so it can be
+// delivered to connected clients.
+type EventPublisher interface {
+	ChannelMessageCreated(message *model.ChannelMessage)
+	ChannelMessageUpdated(message *model.ChannelMessage)
+	ChannelMessageDeleted(channelID, messageID domain.EntityID)
+	PrivateMessageCreated(message *model.PrivateMessage)
+	ChannelMemberAdded(channelID, userID domain.EntityID)
+	ChannelMemberRemoved(channelID, userID domain.EntityID)
+	ChannelMemberRoleChanged(channelID, userID domain.EntityID, role model.ChannelRole)
+	ChannelDeleted(channelID domain.EntityID)
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
+	"github.com/ruslanguns/go-chat/internal/repository"
+)
+
+type SearchService interface {
+	// SearchMessages finds the channel and private messages matching search
+	// that actor can read, most relevant first.
+	SearchMessages(ctx context.Context, actor *model.User, search model.MessageSearch, page pagination.Page) (*pagination.Result[*model.MessageSearchHit], error)
+}
+
+type searchService struct {
+	searchRepo  repository.SearchRepository
+	channelRepo repository.ChannelRepository
+	authorizer  Authorizer
+}
+
+func NewSearchService(searchRepo repository.SearchRepository, channelRepo repository.ChannelRepository, authorizer Authorizer) SearchService {
+	return &searchService{
+		searchRepo:  searchRepo,
+		channelRepo: channelRepo,
+		authorizer:  authorizer,
+	}
+}
+
+func (s *searchService) SearchMessages(ctx context.Context, actor *model.User, search model.MessageSearch, page pagination.Page) (*pagination.Result[*model.MessageSearchHit], error) {
+	ctx, span := tracer.Start(ctx, "SearchService.SearchMessages")
+	defer span.End()
+
+	search.Text = strings.TrimSpace(search.Text)
+	if err := search.Validate(); err != nil {
+		return nil, validationError(err, "Invalid search")
+	}
+	if page.After != nil {
+		return nil, errors.NewAppError(errors.ErrInvalidInput, "Search results are paged by offset, not cursor")
+	}
+
+	if !search.ChannelID.IsZero() {
+		if err := readableChannel(ctx, s.channelRepo, s.authorizer, actor, search.ChannelID); err != nil {
+			return nil, err
+		}
+	}
+
+	allChannels := s.authorizer.CanAdminister(actor) == nil
+	return s.searchRepo.SearchMessages(ctx, actor.ID, allChannels, search, page)
+}
@@ -1,39 +1,53 @@
 package service
 
 import (
+	"context"
+
 	"github.com/ruslanguns/go-chat/internal/domain"
 	"github.com/ruslanguns/go-chat/internal/domain/model"
-	"github.com/ruslanguns/go-chat/internal/errors"
+	"github.com/ruslanguns/go-chat/internal/pagination"
 	"github.com/ruslanguns/go-chat/internal/repository"
 )
 
 type UserService interface {
-	CreateUser(username, email string) (*model.User, error)
-	GetUserByID(id domain.EntityID) (*model.User, error)
-	GetUserByUsername(username string) (*model.User, error)
-	GetUserByEmail(email string) (*model.User, error)
-	UpdateUser(user *model.User) error
-	DeleteUser(id domain.EntityID) error
-	ListUsers(offset, limit int) ([]*model.User, error)
+	CreateUser(ctx context.Context, username, email, password string) (*model.User, error)
+	GetUserByID(ctx context.Context, id domain.EntityID) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	UpdateUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64, username, email string) (*model.User, error)
+	PatchUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64, patch UserPatch) (*model.User, error)
+	DeleteUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64) error
+	ListUsers(ctx context.Context, page pagination.Page) (*pagination.Result[*model.User], error)
+}
+
+// UserPatch lists the user fields to change; nil fields are left unchanged.
+type UserPatch struct {
+	Username *string
+	Email    *string
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	authorizer Authorizer
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, authorizer Authorizer) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		authorizer: authorizer,
 	}
 }
 
-func (s *userService) CreateUser(username, email string) (*model.User, error) {
-	user, err := model.NewUser(username, email, "")
+func (s *userService) CreateUser(ctx context.Context, username, email, password string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	user, err := model.NewUser(username, email, password)
 	if err != nil {
-		return nil, errors.NewAppError(errors.ErrInvalidInput, "Invalid user data")
+		return nil, validationError(err, "Invalid user data")
 	}
 
-	err = s.userRepo.Create(user)
+	err = s.userRepo.Create(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -41,29 +55,96 @@ func (s *userService) CreateUser(username, email string) (*model.User, error) {
 	return user, nil
 }
 
-func (s *userService) GetUserByID(id domain.EntityID) (*model.User, error) {
-	return s.userRepo.GetByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id domain.EntityID) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	return s.userRepo.GetByID(ctx, id)
 }
 
-func (s *userService) GetUserByUsername(username string) (*model.User, error) {
-	return s.userRepo.GetByUsername(username)
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByUsername")
+	defer span.End()
+
+	return s.userRepo.GetByUsername(ctx, model.NormalizeUsername(username))
+}
+
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
+	return s.userRepo.GetByEmail(ctx, model.NormalizeEmail(email))
 }
 
-func (s *userService) GetUserByEmail(email string) (*model.User, error) {
-	return s.userRepo.GetByEmail(email)
+// UpdateUser replaces every user-editable field of the user, provided it is
+// still at the given version.
+func (s *userService) UpdateUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64, username, email string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	return s.PatchUser(ctx, actor, id, version, UserPatch{Username: &username, Email: &email})
 }
 
-func (s *userService) UpdateUser(user *model.User) error {
-	if err := user.Validate(); err != nil {
-		return errors.NewAppError(errors.ErrInvalidInput, "Invalid user data")
+// PatchUser changes only the fields set in patch, provided the user is still
+// at the given version.
+func (s *userService) PatchUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64, patch UserPatch) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.PatchUser")
+	defer span.End()
+
+	if err := s.authorizer.CanModifyUser(actor, id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(&user.BaseEntity, version); err != nil {
+		return nil, err
+	}
+
+	if patch.Username != nil {
+		if err := user.ChangeUsername(*patch.Username); err != nil {
+			return nil, validationError(err, "Invalid user data")
+		}
 	}
-	return s.userRepo.Update(user)
+	if patch.Email != nil {
+		if err := user.ChangeEmail(*patch.Email); err != nil {
+			return nil, validationError(err, "Invalid user data")
+		}
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
-func (s *userService) DeleteUser(id domain.EntityID) error {
-	return s.userRepo.Delete(id)
+func (s *userService) DeleteUser(ctx context.Context, actor *model.User, id domain.EntityID, version int64) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	if err := s.authorizer.CanModifyUser(actor, id); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkVersion(&user.BaseEntity, version); err != nil {
+		return err
+	}
+
+	return s.userRepo.Delete(ctx, id, user.Version)
 }
 
-func (s *userService) ListUsers(offset, limit int) ([]*model.User, error) {
-	return s.userRepo.List(offset, limit)
+func (s *userService) ListUsers(ctx context.Context, page pagination.Page) (*pagination.Result[*model.User], error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
+	return s.userRepo.List(ctx, page)
 }
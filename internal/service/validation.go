@@ -0,0 +1,21 @@
+package service
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+// validationError converts an error returned by a domain model's validation
+// into an AppError. Field errors become an ErrValidation listing the fields;
+// anything else is reported as ErrInvalidInput with msg.
+func validationError(err error, msg string) error {
+	var fields domain.FieldErrors
+	if errors.As(err, &fields) {
+		return errors.NewAppError(errors.ErrValidation, "Validation failed").WithDetails(fields)
+	}
+	var field domain.FieldError
+	if errors.As(err, &field) {
+		return errors.NewAppError(errors.ErrValidation, "Validation failed").WithDetails(domain.FieldErrors{field})
+	}
+	return errors.Wrap(errors.ErrInvalidInput, msg, err)
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+// AnyVersion may be passed as the expected version of a write to skip the
+// version check.
+const AnyVersion int64 = 0
+
+// checkVersion returns ErrPreconditionFailed unless expected is AnyVersion
+// or the entity's current version.
+func checkVersion(entity *domain.BaseEntity, expected int64) error {
+	if expected != AnyVersion && entity.Version != expected {
+		return errors.NewAppError(errors.ErrPreconditionFailed, "The resource has been modified since it was fetched")
+	}
+	return nil
+}
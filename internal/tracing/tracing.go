@@ -0,0 +1,81 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider and its
+// exporter, W3C trace context propagation and the server span of each HTTP
+// request.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ruslanguns/go-chat/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName identifies the application in exported spans.
+const ServiceName = "go-chat"
+
+// Setup installs the tracer provider and propagator configured by cfg. The
+// returned function flushes pending spans and stops the exporter; it must be
+// called before the process exits.
+func Setup(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case config.ExporterNone, "":
+		Install(nil)
+		return func(context.Context) error { return nil }, nil
+	case config.ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case config.ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	tp := NewProvider(sdktrace.WithBatcher(exporter), cfg.SampleRatio)
+	Install(tp)
+	return tp.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider that records the given fraction of
+// new traces and hands spans to processor. Tests pass a synchronous
+// processor around an in-memory exporter.
+func NewProvider(processor sdktrace.TracerProviderOption, sampleRatio float64) *sdktrace.TracerProvider {
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		res = resource.Default()
+	}
+
+	return sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+}
+
+// Install makes tp the global tracer provider, unless it is nil, and sets
+// the W3C trace context and baggage propagator.
+func Install(tp *sdktrace.TracerProvider) {
+	if tp != nil {
+		otel.SetTracerProvider(tp)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
@@ -0,0 +1,122 @@
+// Package validation checks request DTOs against the rules declared in their
+// `validate` struct tags.
+//
+// Besides the built-in validator tags, the following domain tags are
+// available; each normalizes the value the same way the domain model does
+// before checking it:
+//
+//	username      model.ValidateUsername
+//	email5322     model.ValidateEmail
+//	channel_name  model.ValidateChannelName
+//	content       model.ValidateMessageContent
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/domain/model"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+// stringRule is a domain rule applied to a string field by a custom tag.
+type stringRule struct {
+	normalize func(string) string
+	validate  func(string) error
+}
+
+var stringRules = map[string]stringRule{
+	"username":     {model.NormalizeUsername, model.ValidateUsername},
+	"email5322":    {model.NormalizeEmail, model.ValidateEmail},
+	"channel_name": {model.NormalizeChannelName, model.ValidateChannelName},
+	"content":      {strings.TrimSpace, model.ValidateMessageContent},
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON names, which are what clients send.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	for tag, rule := range stringRules {
+		v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return rule.validate(rule.normalize(fl.Field().String())) == nil
+		})
+	}
+
+	return v
+}
+
+// Struct validates s, returning an ErrValidation AppError whose details list
+// every invalid field, or nil when s is valid.
+func Struct(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return errors.Wrap(errors.ErrInternal, "Failed to validate request", err)
+	}
+
+	fields := make(domain.FieldErrors, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, domain.FieldError{Field: fieldPath(fe), Message: message(fe)})
+	}
+	return errors.NewAppError(errors.ErrValidation, "Validation failed").WithDetails(fields)
+}
+
+// fieldPath returns the dotted JSON path of the field, without the name of
+// the top-level struct.
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func message(fe validator.FieldError) string {
+	if rule, ok := stringRules[fe.Tag()]; ok {
+		if err := rule.validate(rule.normalize(fmt.Sprint(fe.Value()))); err != nil {
+			return err.Error()
+		}
+	}
+
+	switch fe.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	}
+	return "is invalid"
+}
@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/ruslanguns/go-chat/internal/domain"
+	"github.com/ruslanguns/go-chat/internal/errors"
+)
+
+type testRequest struct {
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email5322"`
+	Channel  string `json:"channel" validate:"omitempty,channel_name"`
+	Count    int    `json:"count" validate:"gte=0,lte=10"`
+}
+
+func fieldsOf(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var appErr errors.AppError
+	if !errors.As(err, &appErr) || !errors.Is(err, errors.ErrValidation) {
+		t.Fatalf("expected validation error; got %v", err)
+	}
+	fields := map[string]string{}
+	for _, fe := range appErr.Details.(domain.FieldErrors) {
+		fields[fe.Field] = fe.Message
+	}
+	return fields
+}
+
+func TestStructValid(t *testing.T) {
+	req := testRequest{Username: "Alice_1", Email: "alice@example.com", Channel: "General-Chat", Count: 3}
+	if err := Struct(&req); err != nil {
+		t.Errorf("expected request to be valid; got %v", err)
+	}
+}
+
+func TestStructFieldErrors(t *testing.T) {
+	req := testRequest{Username: "a!", Email: "Alice <alice@example.com>", Channel: "no--double", Count: 11}
+	fields := fieldsOf(t, Struct(&req))
+
+	for _, field := range []string{"username", "email", "channel", "count"} {
+		if fields[field] == "" {
+			t.Errorf("expected an error for %s; got %v", field, fields)
+		}
+	}
+	if fields["count"] != "must be less than or equal to 10" {
+		t.Errorf("unexpected count message %q", fields["count"])
+	}
+}
+
+func TestStructRequired(t *testing.T) {
+	fields := fieldsOf(t, Struct(&testRequest{}))
+	if fields["username"] != "is required" || fields["email"] != "is required" {
+		t.Errorf("expected required errors; got %v", fields)
+	}
+	if _, ok := fields["channel"]; ok {
+		t.Errorf("expected optional channel to be skipped; got %v", fields)
+	}
+}